@@ -0,0 +1,254 @@
+package pathlib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ArchiveFormat is the format of an archive.
+type ArchiveFormat int
+
+const (
+	// ArchiveTar is an uncompressed tar archive.
+	ArchiveTar ArchiveFormat = iota
+	// ArchiveTarGz is a gzip compressed tar archive.
+	ArchiveTarGz
+	// ArchiveZip is a zip archive.
+	ArchiveZip
+)
+
+// String returns the customary file extension of the format, without the
+// leading dot.
+func (f ArchiveFormat) String() string {
+	switch f {
+	case ArchiveTar:
+		return "tar"
+	case ArchiveTarGz:
+		return "tar.gz"
+	case ArchiveZip:
+		return "zip"
+	default:
+		return fmt.Sprintf("ArchiveFormat(%d)", int(f))
+	}
+}
+
+// ReproducibleModTime is the modification time given to every entry by
+// ArchiveReproducible. It's the earliest time that can be represented in a zip
+// archive.
+var ReproducibleModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ArchiveOpts is the configuration of Path.ArchiveTo.
+type ArchiveOpts struct {
+	// WalkOpts configure the walk of the tree, determining which objects are
+	// archived. The walk is always performed with AlgorithmPreOrderDepthFirst, so
+	// that directories precede their contents.
+	WalkOpts []WalkOptsFunc
+
+	// ModTime, if not zero, is used as the modification time of every entry.
+	ModTime time.Time
+
+	// SortEntries sorts the children of every directory by name.
+	SortEntries bool
+
+	// ZeroOwner omits the user and group IDs and names of the owners of the
+	// archived objects.
+	ZeroOwner bool
+}
+
+// ArchiveOptsFunc is a function that modifies ArchiveOpts.
+type ArchiveOptsFunc func(opts *ArchiveOpts)
+
+func ArchiveWalkOpts(opts ...WalkOptsFunc) ArchiveOptsFunc {
+	return func(config *ArchiveOpts) {
+		config.WalkOpts = append(config.WalkOpts, opts...)
+	}
+}
+
+func ArchiveModTime(modTime time.Time) ArchiveOptsFunc {
+	return func(config *ArchiveOpts) {
+		config.ModTime = modTime
+	}
+}
+
+func ArchiveSortEntries(value bool) ArchiveOptsFunc {
+	return func(config *ArchiveOpts) {
+		config.SortEntries = value
+	}
+}
+
+func ArchiveZeroOwner(value bool) ArchiveOptsFunc {
+	return func(config *ArchiveOpts) {
+		config.ZeroOwner = value
+	}
+}
+
+// ArchiveReproducible sorts the entries, omits their owners and sets their
+// modification times to ReproducibleModTime, so that archiving the same tree
+// always produces byte-identical output.
+func ArchiveReproducible() ArchiveOptsFunc {
+	return func(config *ArchiveOpts) {
+		config.ModTime = ReproducibleModTime
+		config.SortEntries = true
+		config.ZeroOwner = true
+	}
+}
+
+// archiveWriter writes the entries of an archive.
+type archiveWriter interface {
+	// writeEntry writes an entry named name, which is "/"-separated, with a
+	// trailing "/" for directories.
+	writeEntry(name string, entry *WalkEntry, linkTarget string) error
+	Close() error
+}
+
+// ArchiveTo writes the tree beneath the path to w as an archive of the given
+// format. The path itself is not part of the archive, and the names of the
+// entries are relative to it. Modes and modification times are preserved, and
+// symlinks are archived as symlinks unless the walk follows them. Objects that
+// can't be represented in the format, such as sockets, cause an error and can be
+// excluded with the walk options, for instance WalkVisitSockets(false).
+func (p *Path) ArchiveTo(w io.Writer, format ArchiveFormat, opts ...ArchiveOptsFunc) error {
+	config := &ArchiveOpts{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	walkOpts := append([]WalkOptsFunc{}, config.WalkOpts...)
+	walkOpts = append(walkOpts, WalkAlgorithm(AlgorithmPreOrderDepthFirst))
+	if config.SortEntries {
+		walkOpts = append(walkOpts, WalkSortChildren(true))
+	}
+	walker, err := NewWalk(p, walkOpts...)
+	if err != nil {
+		return err
+	}
+
+	var writer archiveWriter
+	switch format {
+	case ArchiveTar:
+		writer = &tarArchiveWriter{Writer: tar.NewWriter(w), opts: config}
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(w)
+		writer = &tarArchiveWriter{Writer: tar.NewWriter(gz), opts: config, gzip: gz}
+	case ArchiveZip:
+		writer = &zipArchiveWriter{Writer: zip.NewWriter(w), opts: config}
+	default:
+		return fmt.Errorf("unknown archive format %s", format)
+	}
+
+	err = walker.WalkEx(func(entry *WalkEntry) error {
+		if entry.Err != nil {
+			return entry.Err
+		}
+		name := entry.Relative.String()
+		if entry.Info.IsDir() {
+			name += "/"
+		}
+		var linkTarget string
+		if IsSymlink(entry.Info.Mode()) {
+			target, err := entry.Path.Readlink()
+			if err != nil {
+				return fmt.Errorf("archiving %s: %w", name, err)
+			}
+			linkTarget = target.String()
+		}
+		if err := writer.writeEntry(name, entry, linkTarget); err != nil {
+			return fmt.Errorf("archiving %s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// copyFileTo copies the contents of the file described by entry to w.
+func copyFileTo(w io.Writer, entry *WalkEntry) error {
+	file, err := entry.Path.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+type tarArchiveWriter struct {
+	*tar.Writer
+	opts *ArchiveOpts
+	// gzip is the compressor the tar archive is written to, if any.
+	gzip *gzip.Writer
+}
+
+func (t *tarArchiveWriter) writeEntry(name string, entry *WalkEntry, linkTarget string) error {
+	header, err := tar.FileInfoHeader(entry.Info, linkTarget)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if !t.opts.ModTime.IsZero() {
+		header.ModTime = t.opts.ModTime
+		header.AccessTime = time.Time{}
+		header.ChangeTime = time.Time{}
+	}
+	if t.opts.ZeroOwner {
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+	}
+	if err := t.WriteHeader(header); err != nil {
+		return err
+	}
+	if header.Typeflag == tar.TypeReg {
+		return copyFileTo(t.Writer, entry)
+	}
+	return nil
+}
+
+func (t *tarArchiveWriter) Close() error {
+	err := t.Writer.Close()
+	if t.gzip != nil {
+		if gzipErr := t.gzip.Close(); err == nil {
+			err = gzipErr
+		}
+	}
+	return err
+}
+
+type zipArchiveWriter struct {
+	*zip.Writer
+	opts *ArchiveOpts
+}
+
+func (z *zipArchiveWriter) writeEntry(name string, entry *WalkEntry, linkTarget string) error {
+	mode := entry.Info.Mode()
+	if !IsFile(mode) && !IsDir(mode) && !IsSymlink(mode) {
+		return fmt.Errorf("%s is not supported by zip archives", FileTypeOf(mode))
+	}
+	header, err := zip.FileInfoHeader(entry.Info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if IsFile(mode) {
+		header.Method = zip.Deflate
+	}
+	if !z.opts.ModTime.IsZero() {
+		header.Modified = z.opts.ModTime
+	}
+	w, err := z.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	switch {
+	case IsSymlink(mode):
+		_, err = io.WriteString(w, linkTarget)
+	case IsFile(mode):
+		err = copyFileTo(w, entry)
+	}
+	return err
+}
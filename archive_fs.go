@@ -0,0 +1,547 @@
+package pathlib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// archiveNode is an object in an archive.
+type archiveNode struct {
+	info       os.FileInfo
+	children   []*archiveNode
+	linkTarget string
+	// section provides random access to the contents of a file, if the archive
+	// allows it.
+	section *io.SectionReader
+	// open returns a reader of the contents of a file.
+	open func() (io.ReadCloser, error)
+}
+
+// archiveDirInfo describes a directory that is implied by the names of the
+// entries in an archive, but has no entry itself.
+type archiveDirInfo struct {
+	name string
+}
+
+func (i archiveDirInfo) Name() string       { return i.name }
+func (i archiveDirInfo) Size() int64        { return 0 }
+func (i archiveDirInfo) Mode() os.FileMode  { return os.ModeDir | 0o755 }
+func (i archiveDirInfo) ModTime() time.Time { return time.Time{} }
+func (i archiveDirInfo) IsDir() bool        { return true }
+func (i archiveDirInfo) Sys() interface{}   { return nil }
+
+// archiveLinkInfo describes a hard link in a tar archive, which shares the
+// os.FileInfo of the entry it links to, except for its name.
+type archiveLinkInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i archiveLinkInfo) Name() string { return i.name }
+
+// archiveFs is a read-only afero.Fs that serves the contents of an archive.
+type archiveFs struct {
+	readOnlyFs
+	nodes map[string]*archiveNode
+	// file is the archive, if it's kept open to read from it.
+	file afero.File
+}
+
+// OpenArchive returns a Path at the root of a read-only afero.Fs that serves the
+// contents of the zip, tar or gzip compressed tar archive at p, without
+// extracting it. The format is detected from the contents of the archive. The
+// archive is indexed when it's opened, so that the entries of a tar archive
+// can be found without reading through all of it. Symlink entries are exposed
+// as symlinks, which are followed within the archive.
+//
+// Zip and uncompressed tar archives are kept open for reading, and the
+// filesystem implements io.Closer to close them. Reading an entry of a
+// compressed tar archive decompresses the archive up to that entry, and seeking
+// within it reads the entry into memory.
+func OpenArchive(p *Path) (*Path, error) {
+	file, err := p.Open()
+	if err != nil {
+		return nil, err
+	}
+	fs := &archiveFs{
+		nodes: map[string]*archiveNode{
+			".": {info: archiveDirInfo{name: "/"}},
+		},
+	}
+	format, size, err := detectArchiveFormat(file)
+	if err == nil {
+		switch format {
+		case ArchiveZip:
+			fs.file = file
+			err = fs.indexZip(file, size)
+		case ArchiveTar:
+			fs.file = file
+			err = fs.indexTar(file, true, nil)
+		case ArchiveTarGz:
+			var gz *gzip.Reader
+			gz, err = gzip.NewReader(file)
+			if err == nil {
+				err = fs.indexTar(gz, false, p)
+			}
+		}
+	}
+	if fs.file == nil || err != nil {
+		file.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening archive %s: %w", p, err)
+	}
+	fs.sortChildren()
+	return NewPath("/", PathWithAfero(fs)), nil
+}
+
+// detectArchiveFormat determines the format of the archive from its first bytes,
+// and returns its size. The file is positioned at its start afterwards.
+func detectArchiveFormat(file afero.File) (ArchiveFormat, int64, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	header := make([]byte, 512)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, 0, err
+	}
+	header = header[:n]
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ArchiveZip, size, nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return ArchiveTarGz, size, nil
+	case len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar")):
+		return ArchiveTar, size, nil
+	}
+	return 0, 0, errors.New("unknown archive format")
+}
+
+// archiveName converts the name of an archive entry, or an afero name, to the
+// key of its node in archiveFs.nodes. Names can't refer to anything outside of
+// the archive, so leading ".." components are dropped.
+func archiveName(name string) string {
+	name = path.Clean("/" + filepath.ToSlash(name))
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
+
+// add adds a node for the entry with the given name, creating its parent
+// directories if necessary. A later entry with the same name replaces an
+// earlier one.
+func (a *archiveFs) add(name string, node *archiveNode) {
+	key := archiveName(name)
+	if key == "." {
+		return
+	}
+	parent := a.parent(key)
+	if existing, ok := a.nodes[key]; ok {
+		node.children = existing.children
+		parent.children[slices.Index(parent.children, existing)] = node
+	} else {
+		parent.children = append(parent.children, node)
+	}
+	a.nodes[key] = node
+}
+
+// parent returns the node of the parent directory of name, creating it if it
+// doesn't exist.
+func (a *archiveFs) parent(name string) *archiveNode {
+	dir := path.Dir(name)
+	if node, ok := a.nodes[dir]; ok {
+		return node
+	}
+	node := &archiveNode{info: archiveDirInfo{name: path.Base(dir)}}
+	a.add(dir, node)
+	return node
+}
+
+func (a *archiveFs) sortChildren() {
+	for _, node := range a.nodes {
+		slices.SortFunc(node.children, func(x, y *archiveNode) int {
+			return strings.Compare(x.info.Name(), y.info.Name())
+		})
+	}
+}
+
+func (a *archiveFs) indexZip(file afero.File, size int64) error {
+	zr, err := zip.NewReader(file, size)
+	if err != nil {
+		return err
+	}
+	for _, entry := range zr.File {
+		entry := entry
+		node := &archiveNode{info: entry.FileInfo()}
+		if IsSymlink(entry.Mode()) {
+			rc, err := entry.Open()
+			if err != nil {
+				return err
+			}
+			target, err := io.ReadAll(io.LimitReader(rc, maxSymlinkTargetSize))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			node.linkTarget = string(target)
+		} else if !entry.FileInfo().IsDir() {
+			node.open = func() (io.ReadCloser, error) {
+				return entry.Open()
+			}
+			if entry.Method == zip.Store {
+				if offset, err := entry.DataOffset(); err == nil {
+					node.section = io.NewSectionReader(file, offset, int64(entry.UncompressedSize64))
+				}
+			}
+		}
+		a.add(entry.Name, node)
+	}
+	return nil
+}
+
+// indexTar indexes the entries of a tar archive read from r. If seekable, r is
+// the archive file itself, and the contents of the entries are read from it
+// directly. Otherwise, they're read by decompressing the archive at p.
+func (a *archiveFs) indexTar(r io.Reader, seekable bool, p *Path) error {
+	tr := tar.NewReader(r)
+	for ordinal := 0; ; ordinal++ {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		node := &archiveNode{info: header.FileInfo()}
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			node.linkTarget = header.Linkname
+		case tar.TypeLink:
+			// A hard link shares the contents of an entry that precedes it.
+			linked, ok := a.nodes[archiveName(header.Linkname)]
+			if !ok || linked.open == nil {
+				continue
+			}
+			node.info = archiveLinkInfo{FileInfo: linked.info, name: path.Base(archiveName(header.Name))}
+			node.open = linked.open
+			node.section = linked.section
+		case tar.TypeReg:
+			if seekable {
+				offset, err := a.file.Seek(0, io.SeekCurrent)
+				if err != nil {
+					return err
+				}
+				section := io.NewSectionReader(a.file, offset, header.Size)
+				node.section = section
+				node.open = func() (io.ReadCloser, error) {
+					return io.NopCloser(io.NewSectionReader(section, 0, section.Size())), nil
+				}
+			} else {
+				node.open = openTarGzEntry(p, ordinal)
+			}
+		case tar.TypeDir:
+		default:
+			// Special files are listed, but have no contents.
+		}
+		a.add(header.Name, node)
+	}
+}
+
+// openTarGzEntry returns a function that opens the entry with the given ordinal
+// of the compressed tar archive at p.
+func openTarGzEntry(p *Path, ordinal int) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		file, err := p.Open()
+		if err != nil {
+			return nil, err
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for i := 0; i <= ordinal; i++ {
+			if _, err := tr.Next(); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{tr, file}, nil
+	}
+}
+
+// lookup returns the node that name refers to, following symlinks in every
+// component of name but the last, and in the last if follow is true. Like
+// secureResolve, it resolves one component at a time, so that every symlink
+// that is followed counts towards a single limit.
+func (a *archiveFs) lookup(op string, name string, follow bool) (*archiveNode, error) {
+	components := strings.Split(archiveName(name), "/")
+	resolved := []string{}
+	node := a.nodes["."]
+	hops := 0
+	for len(components) > 0 {
+		component := components[0]
+		components = components[1:]
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			// The root of the archive is its own parent.
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+			node = a.nodes[archiveName(strings.Join(resolved, "/"))]
+			continue
+		}
+		if !node.info.IsDir() {
+			return nil, &os.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+		}
+		resolved = append(resolved, component)
+		var ok bool
+		node, ok = a.nodes[strings.Join(resolved, "/")]
+		if !ok {
+			return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+		}
+		if !IsSymlink(node.info.Mode()) || (!follow && len(components) == 0) {
+			continue
+		}
+		hops++
+		if hops > maxSymlinkHops {
+			return nil, &os.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+		}
+		target := node.linkTarget
+		resolved = resolved[:len(resolved)-1]
+		if path.IsAbs(target) {
+			resolved = resolved[:0]
+		}
+		node = a.nodes[archiveName(strings.Join(resolved, "/"))]
+		components = append(strings.Split(target, "/"), components...)
+	}
+	return node, nil
+}
+
+// Name implements afero.Fs.
+func (a *archiveFs) Name() string {
+	return "archiveFs"
+}
+
+// Close closes the archive, if it's kept open.
+func (a *archiveFs) Close() error {
+	if a.file == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+// Open implements afero.Fs.
+func (a *archiveFs) Open(name string) (afero.File, error) {
+	node, err := a.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &archiveFile{readOnlyFile: readOnlyFile{name: name}, node: node}, nil
+}
+
+// OpenFile implements afero.Fs. Only files opened for reading are supported.
+func (a *archiveFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if err := checkReadOnlyFlag(name, flag); err != nil {
+		return nil, err
+	}
+	return a.Open(name)
+}
+
+// Stat implements afero.Fs.
+func (a *archiveFs) Stat(name string) (os.FileInfo, error) {
+	node, err := a.lookup("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return node.info, nil
+}
+
+// LstatIfPossible implements afero.Lstater.
+func (a *archiveFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	node, err := a.lookup("lstat", name, false)
+	if err != nil {
+		return nil, true, err
+	}
+	return node.info, true, nil
+}
+
+// ReadlinkIfPossible implements afero.LinkReader.
+func (a *archiveFs) ReadlinkIfPossible(name string) (string, error) {
+	node, err := a.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if !IsSymlink(node.info.Mode()) {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return node.linkTarget, nil
+}
+
+// archiveFile is an open object of an archiveFs.
+type archiveFile struct {
+	readOnlyFile
+	node *archiveNode
+	// reader reads the contents sequentially, until random access is needed.
+	reader io.ReadCloser
+	// random provides random access to the contents.
+	random *io.SectionReader
+	offset int64
+	// dirOffset is the number of children that have been read by Readdir.
+	dirOffset int
+}
+
+func (f *archiveFile) pathError(op string, err error) error {
+	return &os.PathError{Op: op, Path: f.name, Err: err}
+}
+
+// randomAccess returns a reader with random access to the contents, reading them
+// into memory if the archive doesn't provide random access.
+func (f *archiveFile) randomAccess() (*io.SectionReader, error) {
+	if f.random != nil {
+		return f.random, nil
+	}
+	if f.node.open == nil {
+		return nil, f.pathError("read", syscall.EISDIR)
+	}
+	if f.node.section != nil {
+		f.random = io.NewSectionReader(f.node.section, 0, f.node.section.Size())
+		return f.random, nil
+	}
+	rc, err := f.node.open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	f.random = io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data)))
+	return f.random, nil
+}
+
+// Read implements afero.File.
+func (f *archiveFile) Read(p []byte) (int, error) {
+	if f.random == nil && f.node.section == nil {
+		if f.node.open == nil {
+			return 0, f.pathError("read", syscall.EISDIR)
+		}
+		if f.reader == nil {
+			rc, err := f.node.open()
+			if err != nil {
+				return 0, err
+			}
+			f.reader = rc
+		}
+		n, err := f.reader.Read(p)
+		f.offset += int64(n)
+		return n, err
+	}
+	random, err := f.randomAccess()
+	if err != nil {
+		return 0, err
+	}
+	n, err := random.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadAt implements afero.File.
+func (f *archiveFile) ReadAt(p []byte, off int64) (int, error) {
+	random, err := f.randomAccess()
+	if err != nil {
+		return 0, err
+	}
+	return random.ReadAt(p, off)
+}
+
+// Seek implements afero.File.
+func (f *archiveFile) Seek(offset int64, whence int) (int64, error) {
+	random, err := f.randomAccess()
+	if err != nil {
+		return 0, err
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += random.Size()
+	}
+	if offset < 0 {
+		return 0, f.pathError("seek", syscall.EINVAL)
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Readdir implements afero.File.
+func (f *archiveFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.node.info.IsDir() {
+		return nil, f.pathError("readdir", syscall.ENOTDIR)
+	}
+	children := f.node.children[f.dirOffset:]
+	if count > 0 {
+		if len(children) == 0 {
+			return nil, io.EOF
+		}
+		if len(children) > count {
+			children = children[:count]
+		}
+	}
+	f.dirOffset += len(children)
+	infos := make([]os.FileInfo, 0, len(children))
+	for _, child := range children {
+		infos = append(infos, child.info)
+	}
+	return infos, nil
+}
+
+// Readdirnames implements afero.File.
+func (f *archiveFile) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names, err
+}
+
+// Stat implements afero.File.
+func (f *archiveFile) Stat() (os.FileInfo, error) {
+	return f.node.info, nil
+}
+
+// Close implements afero.File.
+func (f *archiveFile) Close() error {
+	if f.reader != nil {
+		return f.reader.Close()
+	}
+	return nil
+}
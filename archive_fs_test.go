@@ -0,0 +1,180 @@
+package pathlib
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newArchiveFile writes the tree of newArchiveTree, with a symlink, as an
+// archive in the given format and returns its path.
+func newArchiveFile(t *testing.T, format ArchiveFormat) *Path {
+	fs := afero.NewMemMapFs()
+	root := NewPathAfero("/tree", fs)
+	newArchiveTree(t, root)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, root.ArchiveTo(buf, format))
+	archive := NewPathAfero("/archive."+format.String(), fs)
+	require.NoError(t, archive.WriteFile(buf.Bytes()))
+	return archive
+}
+
+func TestOpenArchive(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveTarGz, ArchiveZip} {
+		t.Run(format.String(), func(t *testing.T) {
+			root, err := OpenArchive(newArchiveFile(t, format))
+			require.NoError(t, err)
+			defer root.Fs().(io.Closer).Close()
+
+			children, err := root.Join("dir").ReadDir()
+			require.NoError(t, err)
+			names := []string{}
+			for _, child := range children {
+				names = append(names, child.Name())
+			}
+			assert.ElementsMatch(t, []string{"b.txt", "build.log", "sub"}, names)
+
+			contents, err := root.Join("dir/sub/c.txt").ReadFile()
+			require.NoError(t, err)
+			assert.Equal(t, "ccc", string(contents))
+
+			info, err := root.Join("a.txt").Stat()
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+			matches, err := root.Glob("dir/*.txt")
+			require.NoError(t, err)
+			require.Len(t, matches, 1)
+			assert.Equal(t, "/dir/b.txt", matches[0].String())
+
+			walk, err := NewWalk(root, WalkSortChildren(true))
+			require.NoError(t, err)
+			count, err := walk.Count()
+			require.NoError(t, err)
+			assert.Equal(t, 6, count)
+
+			_, err = root.Join("missing").Stat()
+			assert.True(t, errors.Is(err, os.ErrNotExist))
+			assert.True(t, errors.Is(root.Join("new").WriteFile([]byte("new")), syscall.EPERM))
+			assert.True(t, errors.Is(root.Join("a.txt").Remove(), syscall.EPERM))
+		})
+	}
+}
+
+func TestOpenArchiveSymlinks(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	archive := NewPathAfero("/archive.tar", fs)
+	require.NoError(t, archive.WriteFile(newTar(t,
+		tarRegular("dir/file.txt", "file"),
+		tarSymlink("link", "dir/file.txt"),
+		tarSymlink("dirlink", "dir"),
+		tarSymlink("loop", "loop"),
+		tarEntry{tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "dir/file.txt"}, ""},
+	)))
+	root, err := OpenArchive(archive)
+	require.NoError(t, err)
+
+	info, err := root.Join("link").Lstat()
+	require.NoError(t, err)
+	assert.True(t, IsSymlink(info.Mode()))
+	target, err := root.Join("link").Readlink()
+	require.NoError(t, err)
+	assert.Equal(t, "dir/file.txt", target.String())
+
+	for _, name := range []string{"link", "dirlink/file.txt", "hard"} {
+		contents, err := root.Join(name).ReadFile()
+		require.NoError(t, err, name)
+		assert.Equal(t, "file", string(contents), name)
+	}
+
+	_, err = root.Join("loop").Stat()
+	assert.True(t, errors.Is(err, syscall.ELOOP))
+	_, err = root.Join("link/file.txt").Stat()
+	assert.True(t, errors.Is(err, syscall.ENOTDIR), err)
+	_, err = root.Join("dir/file.txt").Readlink()
+	assert.True(t, errors.Is(err, syscall.EINVAL))
+}
+
+func TestOpenArchiveHardLink(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	archive := NewPathAfero("/archive.tar", fs)
+	require.NoError(t, archive.WriteFile(newTar(t,
+		tarRegular("dir/orig.txt", "file"),
+		tarEntry{tar.Header{Name: "dir/zlink.txt", Typeflag: tar.TypeLink, Linkname: "dir/orig.txt"}, ""},
+	)))
+	root, err := OpenArchive(archive)
+	require.NoError(t, err)
+
+	// The hard link has the name of its own entry, not of the one it links to.
+	info, err := root.Join("dir", "zlink.txt").Stat()
+	require.NoError(t, err)
+	assert.Equal(t, "zlink.txt", info.Name())
+	assert.Equal(t, int64(4), info.Size())
+
+	children, err := root.Join("dir").ReadDir()
+	require.NoError(t, err)
+	names := []string{}
+	for _, child := range children {
+		names = append(names, child.Name())
+	}
+	assert.Equal(t, []string{"orig.txt", "zlink.txt"}, names)
+}
+
+func TestOpenArchiveParentSymlinkLoop(t *testing.T) {
+	archive := NewPathAfero("/archive.tar", afero.NewMemMapFs())
+	require.NoError(t, archive.WriteFile(newTar(t, tarSymlink("l", "l/x"))))
+	root, err := OpenArchive(archive)
+	require.NoError(t, err)
+
+	_, err = root.Join("l").Stat()
+	assert.True(t, errors.Is(err, syscall.ELOOP), err)
+	_, err = root.Join("l", "y").Lstat()
+	assert.True(t, errors.Is(err, syscall.ELOOP), err)
+	info, err := root.Join("l").Lstat()
+	require.NoError(t, err)
+	assert.True(t, IsSymlink(info.Mode()))
+}
+
+func TestOpenArchiveSeek(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveTarGz, ArchiveZip} {
+		t.Run(format.String(), func(t *testing.T) {
+			root, err := OpenArchive(newArchiveFile(t, format))
+			require.NoError(t, err)
+			defer root.Fs().(io.Closer).Close()
+
+			file, err := root.Join("dir/sub/c.txt").Open()
+			require.NoError(t, err)
+			defer file.Close()
+
+			buf := make([]byte, 1)
+			_, err = file.Read(buf)
+			require.NoError(t, err)
+			offset, err := file.Seek(1, io.SeekCurrent)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), offset)
+			rest, err := io.ReadAll(file)
+			require.NoError(t, err)
+			assert.Equal(t, "c", string(rest))
+
+			n, err := file.ReadAt(buf, 0)
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+		})
+	}
+}
+
+func TestOpenArchiveUnknownFormat(t *testing.T) {
+	archive := NewPathAfero("/archive", afero.NewMemMapFs())
+	require.NoError(t, archive.WriteFile([]byte("not an archive")))
+	_, err := OpenArchive(archive)
+	assert.Error(t, err)
+}
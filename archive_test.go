@@ -0,0 +1,155 @@
+package pathlib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archivedEntry is an entry read back from an archive.
+type archivedEntry struct {
+	name     string
+	mode     os.FileMode
+	contents string
+	modTime  time.Time
+}
+
+// readArchive returns the entries of the archive, in order. The contents of
+// symlinks are their targets.
+func readArchive(t *testing.T, data []byte, format ArchiveFormat) []archivedEntry {
+	entries := []archivedEntry{}
+	switch format {
+	case ArchiveTar, ArchiveTarGz:
+		var r io.Reader = bytes.NewReader(data)
+		if format == ArchiveTarGz {
+			gz, err := gzip.NewReader(r)
+			require.NoError(t, err)
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			contents, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			if header.Typeflag == tar.TypeSymlink {
+				contents = []byte(header.Linkname)
+			}
+			entries = append(entries, archivedEntry{header.Name, header.FileInfo().Mode(), string(contents), header.ModTime})
+		}
+	case ArchiveZip:
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		for _, file := range zr.File {
+			rc, err := file.Open()
+			require.NoError(t, err)
+			contents, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			entries = append(entries, archivedEntry{file.Name, file.Mode(), string(contents), file.Modified})
+		}
+	}
+	return entries
+}
+
+func newArchiveTree(t *testing.T, root *Path) {
+	require.NoError(t, FileTree(root, map[string]string{
+		"a.txt":         "a",
+		"dir/b.txt":     "bb",
+		"dir/sub/c.txt": "ccc",
+		"dir/build.log": "log",
+	}))
+	require.NoError(t, root.Join("a.txt").Chmod(0o755))
+}
+
+func TestArchiveTo(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveTarGz, ArchiveZip} {
+		t.Run(format.String(), func(t *testing.T) {
+			root := NewPath("/root", PathWithAfero(afero.NewMemMapFs()))
+			newArchiveTree(t, root)
+			modTime := time.Date(2020, time.February, 3, 4, 5, 6, 0, time.UTC)
+			require.NoError(t, root.Join("dir", "b.txt").Chtimes(modTime, modTime))
+
+			buf := &bytes.Buffer{}
+			require.NoError(t, root.ArchiveTo(buf, format, ArchiveSortEntries(true), ArchiveWalkOpts(WalkExclude("*.log"))))
+
+			entries := readArchive(t, buf.Bytes(), format)
+			names := []string{}
+			for _, entry := range entries {
+				names = append(names, entry.name)
+			}
+			assert.Equal(t, []string{"a.txt", "dir/", "dir/b.txt", "dir/sub/", "dir/sub/c.txt"}, names)
+			assert.Equal(t, os.FileMode(0o755), entries[0].mode.Perm())
+			assert.True(t, entries[1].mode.IsDir())
+			assert.Equal(t, "bb", entries[2].contents)
+			assert.True(t, modTime.Equal(entries[2].modTime), entries[2].modTime)
+			assert.Equal(t, "ccc", entries[4].contents)
+		})
+	}
+}
+
+func TestArchiveToReproducible(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveTarGz, ArchiveZip} {
+		t.Run(format.String(), func(t *testing.T) {
+			archives := [][]byte{}
+			for i := 0; i < 2; i++ {
+				root := NewPath(t.TempDir())
+				newArchiveTree(t, root)
+				modTime := time.Now().Add(time.Duration(i) * time.Hour)
+				require.NoError(t, root.Join("a.txt").Chtimes(modTime, modTime))
+
+				buf := &bytes.Buffer{}
+				require.NoError(t, root.ArchiveTo(buf, format, ArchiveReproducible()))
+				archives = append(archives, buf.Bytes())
+			}
+			assert.Equal(t, archives[0], archives[1])
+			for _, entry := range readArchive(t, archives[0], format) {
+				assert.True(t, ReproducibleModTime.Equal(entry.modTime), entry.name)
+			}
+		})
+	}
+}
+
+func TestArchiveToSymlinks(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, root.Join("file.txt").WriteFile([]byte("file")))
+	require.NoError(t, root.Join("link").SymlinkStr("file.txt"))
+
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveZip} {
+		buf := &bytes.Buffer{}
+		require.NoError(t, root.ArchiveTo(buf, format, ArchiveSortEntries(true)))
+		entries := readArchive(t, buf.Bytes(), format)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "link", entries[1].name)
+		assert.True(t, IsSymlink(entries[1].mode), format)
+		assert.Equal(t, "file.txt", entries[1].contents)
+	}
+}
+
+func TestArchiveToUnsupported(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, root.Join("file.txt").WriteFile([]byte("file")))
+	listenUnix(t, root.Join("sock"))
+
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveZip} {
+		err := root.ArchiveTo(io.Discard, format)
+		require.Error(t, err)
+		assert.True(t, strings.Contains(err.Error(), "sock"), err.Error())
+
+		require.NoError(t, root.ArchiveTo(io.Discard, format, ArchiveWalkOpts(WalkVisitSockets(false))))
+	}
+	assert.Error(t, root.ArchiveTo(io.Discard, ArchiveFormat(42)))
+}
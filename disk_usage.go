@@ -0,0 +1,91 @@
+package pathlib
+
+// DiskUsage describes the space used by the objects in a directory tree, as
+// reported by Path.DiskUsage.
+type DiskUsage struct {
+	// Path is the path the usage was computed for.
+	Path *Path
+	// ApparentSize is the sum of the sizes of the visited objects.
+	ApparentSize int64
+	// Blocks is the number of 512-byte blocks allocated to the visited objects.
+	// If the filesystem does not report allocated blocks, the apparent size of
+	// each object is rounded up to a whole number of blocks instead.
+	Blocks int64
+	// Files is the number of visited objects that are not directories.
+	Files int64
+	// Dirs is the number of visited directories.
+	Dirs int64
+	// Children is the usage of each of the immediate children of Path that
+	// contains visited objects, in the order in which the walk reached them. The
+	// Children of the children themselves are not populated.
+	Children []*DiskUsage
+}
+
+func (d *DiskUsage) add(entry *WalkEntry, blocks int64) {
+	d.ApparentSize += entry.Info.Size()
+	d.Blocks += blocks
+	if entry.Info.IsDir() {
+		d.Dirs++
+	} else {
+		d.Files++
+	}
+}
+
+// fileKey uniquely identifies a file by its device and inode numbers.
+type fileKey struct {
+	dev uint64
+	ino uint64
+}
+
+// DiskUsage walks the tree beneath the path and reports the space used by the
+// objects that are visited, similar to du(1). opts configure the walk, so that
+// the filters of WalkOpts determine which objects are counted. The walk is always
+// performed with AlgorithmPostOrderDepthFirst, meaning the usage of each child
+// is complete by the time the child itself is visited. Files with multiple hard
+// links are counted once, if the filesystem exposes inode numbers.
+func (p *Path) DiskUsage(opts ...WalkOptsFunc) (*DiskUsage, error) {
+	walkOpts := append([]WalkOptsFunc{}, opts...)
+	walker, err := NewWalk(p, append(walkOpts, WalkAlgorithm(AlgorithmPostOrderDepthFirst))...)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &DiskUsage{Path: p}
+	children := map[string]*DiskUsage{}
+	seen := map[fileKey]struct{}{}
+	err = walker.WalkEx(func(entry *WalkEntry) error {
+		blocks, links, ok := fileBlocks(entry.Info)
+		if !ok {
+			blocks = (entry.Info.Size() + 511) / 512
+		}
+		if links > 1 && !entry.Info.IsDir() {
+			dev, ino, ok := fileID(entry.Info)
+			if ok {
+				key := fileKey{dev: dev, ino: ino}
+				if _, counted := seen[key]; counted {
+					return nil
+				}
+				seen[key] = struct{}{}
+			}
+		}
+
+		usage.add(entry, blocks)
+		if entry.Depth < 0 {
+			// The root itself, visited with IncludeRoot.
+			return nil
+		}
+		ancestor := entry
+		for ancestor.Depth > 0 {
+			ancestor = ancestor.Parent
+		}
+		child, ok := children[ancestor.Path.Name()]
+		if !ok {
+			child = &DiskUsage{Path: ancestor.Path}
+			children[ancestor.Path.Name()] = child
+			usage.Children = append(usage.Children, child)
+		}
+		child.add(entry, blocks)
+		return nil
+	})
+	return usage, err
+}
@@ -0,0 +1,102 @@
+package pathlib
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskUsage(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, FileTree(root, map[string]string{
+		"a.txt":       strings.Repeat("x", 10),
+		"b/c.txt":     strings.Repeat("x", 100),
+		"b/d/e.txt":   strings.Repeat("x", 1000),
+		"f/g.txt":     strings.Repeat("x", 5),
+		"f/ignore.me": strings.Repeat("x", 50),
+	}))
+
+	usage, err := root.DiskUsage(WalkVisitDirs(false), WalkSortChildren(true), WalkExclude("*.me"))
+	require.NoError(t, err)
+	assert.Equal(t, root, usage.Path)
+	assert.Equal(t, int64(1115), usage.ApparentSize)
+	assert.Equal(t, int64(4), usage.Files)
+	assert.Equal(t, int64(0), usage.Dirs)
+
+	type child struct {
+		name  string
+		size  int64
+		files int64
+	}
+	children := []child{}
+	var blocks int64
+	for _, c := range usage.Children {
+		assert.Nil(t, c.Children)
+		children = append(children, child{c.Path.Name(), c.ApparentSize, c.Files})
+		blocks += c.Blocks
+	}
+	assert.Equal(t, []child{{"b", 1100, 2}, {"f", 5, 1}, {"a.txt", 10, 1}}, children)
+	assert.Equal(t, usage.Blocks, blocks)
+	assert.Greater(t, usage.Blocks, int64(0))
+}
+
+func TestDiskUsageHardLinks(t *testing.T) {
+	root := NewPath(t.TempDir())
+	file := root.Join("file.txt")
+	require.NoError(t, file.WriteFile([]byte("contents")))
+	if err := os.Link(file.String(), root.Join("link.txt").String()); err != nil {
+		t.Skipf("hard links are not supported: %v", err)
+	}
+	if _, _, ok := fileID(mustStat(t, file)); !ok {
+		t.Skip("filesystem does not expose inode numbers")
+	}
+
+	usage, err := root.DiskUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("contents")), usage.ApparentSize)
+	assert.Equal(t, int64(1), usage.Files)
+}
+
+func TestDiskUsageIncludeRoot(t *testing.T) {
+	root := NewPath("/root", PathWithAfero(afero.NewMemMapFs()))
+	require.NoError(t, root.Join("dir").MkdirAll())
+	require.NoError(t, root.Join("dir", "file.txt").WriteFile([]byte(strings.Repeat("x", 513))))
+
+	usage, err := root.DiskUsage(WalkIncludeRoot(true), WalkVisitDirs(true))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Dirs)
+	assert.Equal(t, int64(1), usage.Files)
+	require.Len(t, usage.Children, 1)
+	assert.Equal(t, int64(1), usage.Children[0].Dirs)
+	// MemMapFs does not report allocated blocks, so they are derived from the
+	// apparent sizes.
+	dirInfo := mustStat(t, root)
+	assert.Equal(t, 2*((dirInfo.Size()+511)/512)+2, usage.Blocks)
+}
+
+func mustStat(t *testing.T, path *Path) os.FileInfo {
+	info, err := path.Stat()
+	require.NoError(t, err)
+	return info
+}
+
+func TestDiskUsageKeepsOpts(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, root.Join("a.txt").WriteFile([]byte("a")))
+
+	// Spare capacity in the caller's slice must not be written to.
+	opts := make([]WalkOptsFunc, 1, 2)
+	opts[0] = WalkVisitDirs(false)
+	spare := opts[:2]
+	spare[1] = WalkAlgorithm(AlgorithmBasic)
+	_, err := root.DiskUsage(opts...)
+	require.NoError(t, err)
+	config := DefaultWalkOpts()
+	config.Algorithm = AlgorithmPreOrderDepthFirst
+	spare[1](config)
+	assert.Equal(t, AlgorithmBasic, config.Algorithm)
+}
@@ -3,6 +3,13 @@ package pathlib
 import "fmt"
 
 var (
+	// ErrArchiveEntryUnsafe indicates that an archive entry could not be extracted
+	// because it would have been written, or would have pointed, outside of the
+	// destination.
+	ErrArchiveEntryUnsafe = fmt.Errorf("unsafe archive entry")
+	// ErrArchiveLimitExceeded indicates that an archive exceeds the limits on its
+	// size or number of entries that it's extracted with.
+	ErrArchiveLimitExceeded = fmt.Errorf("archive limit exceeded")
 	// ErrDoesNotImplement indicates that the afero filesystem doesn't
 	// implement the required interface.
 	ErrDoesNotImplement = fmt.Errorf("doesn't implement required interface")
@@ -10,20 +17,44 @@ var (
 	ErrInfoIsNil = fmt.Errorf("provided os.Info object was nil")
 	// ErrInvalidAlgorithm specifies that an unknown algorithm was given for Walk
 	ErrInvalidAlgorithm = fmt.Errorf("invalid algorithm specified")
+	// ErrInvalidWalkCursor indicates that a WalkCursor could not be decoded, or that
+	// a walk can't be resumed from it.
+	ErrInvalidWalkCursor = fmt.Errorf("invalid walk cursor")
 	// ErrLstatNotPossible specifies that the filesystem does not support lstat-ing
 	ErrLstatNotPossible = fmt.Errorf("lstat is not possible")
+	// ErrSameDeviceNotPossible is returned by Walk when WalkOpts.SameDevice is set, but
+	// the filesystem does not expose device IDs. It serves as a warning: the walk is
+	// performed in full, as if SameDevice was not set.
+	ErrSameDeviceNotPossible = fmt.Errorf("restricting walk to a single device is not possible")
+	// ErrPathEscapesRoot indicates that an operation on a Path confined with
+	// PathWithJail would have accessed an object outside of the jail.
+	ErrPathEscapesRoot = fmt.Errorf("path escapes root")
 	// ErrRelativeTo indicates that we could not make one path relative to another
 	ErrRelativeTo  = fmt.Errorf("failed to make path relative to other")
 	errWalkControl = fmt.Errorf("walk control")
-	// ErrSkipSubtree indicates to the walk function that the current subtree of
-	// directories should be skipped. It's recommended to only use this error
-	// with the AlgorithmPreOrderDepthFirst algorithm, as many other walk algorithms
-	// will not respect this error due to the nature of the ordering in which the
-	// algorithms visit each node of the filesystem tree.
+	// ErrWalkSkipSiblings indicates to the walk function that the remaining children
+	// of the current object's parent directory should be skipped. Siblings that
+	// have already been visited, or recursed into, are unaffected. When returned for
+	// a directory, the directory's own subtree is still walked by the algorithms that
+	// haven't walked it yet. Returned from WalkOpts.OnEnterDir, it additionally skips
+	// the directory itself, unless it has already been visited.
+	ErrWalkSkipSiblings = fmt.Errorf("skip siblings: %w", errWalkControl)
+	// ErrWalkSkipSubtree indicates to the walk function that the subtree of the
+	// current directory should be skipped. Since AlgorithmBasic and
+	// AlgorithmPostOrderDepthFirst visit a directory after its subtree has been
+	// walked, it has no effect on directories with those algorithms, and should be
+	// returned from WalkOpts.OnEnterDir instead. When returned for an object that is
+	// not a directory, it behaves like ErrWalkSkipSiblings, mirroring fs.SkipDir.
 	ErrWalkSkipSubtree = fmt.Errorf("skip subtree: %w", errWalkControl)
 	// ErrStopWalk indicates to the Walk function that the walk should be aborted.
 	// DEPRECATED: Use ErrWalkStop
 	ErrStopWalk = ErrWalkStop
+	// ErrWalkSymlinkLoop is reported to the WalkFunc for a symlinked directory that
+	// would cause the walk to loop, in which case the directory is not recursed into.
+	ErrWalkSymlinkLoop = fmt.Errorf("symlink loop detected")
 	// ErrWalkStop indicates to the Walk function that the walk should be aborted.
 	ErrWalkStop = fmt.Errorf("stop filesystem walk: %w", errWalkControl)
+	// ErrWatchBackendNotSupported indicates that a WatchBackend can't be used to watch
+	// a path, because of its filesystem or the platform.
+	ErrWatchBackendNotSupported = fmt.Errorf("watch backend not supported")
 )
@@ -0,0 +1,471 @@
+package pathlib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExtractOpts is the configuration of Path.ExtractFrom.
+type ExtractOpts struct {
+	// MaxTotalSize is the maximum number of bytes that are extracted, summed over
+	// all entries. If negative, there is no limit. A zip archive that is read from
+	// an io.Reader without random access is buffered in memory, and may then be
+	// at most MaxTotalSize plus 1KiB per entry allowed by MaxEntries in size.
+	MaxTotalSize int64
+
+	// MaxEntries is the maximum number of entries in the archive. If negative,
+	// there is no limit, including on the size of a buffered zip archive.
+	MaxEntries int
+
+	// PreserveModes applies the permission bits of the entries to the extracted
+	// objects. Otherwise, the DefaultFileMode and DefaultDirMode of the destination
+	// are used.
+	PreserveModes bool
+
+	// PreserveTimes applies the modification times of the entries to the
+	// extracted objects.
+	PreserveTimes bool
+}
+
+// DefaultExtractOpts returns the default ExtractOpts used by Path.ExtractFrom,
+// which limit the extracted data to 1GiB in at most 100000 entries.
+func DefaultExtractOpts() *ExtractOpts {
+	return &ExtractOpts{
+		MaxTotalSize: 1 << 30,
+		MaxEntries:   100000,
+	}
+}
+
+// ExtractOptsFunc is a function that modifies ExtractOpts.
+type ExtractOptsFunc func(opts *ExtractOpts)
+
+func ExtractMaxTotalSize(size int64) ExtractOptsFunc {
+	return func(config *ExtractOpts) {
+		config.MaxTotalSize = size
+	}
+}
+
+func ExtractMaxEntries(entries int) ExtractOptsFunc {
+	return func(config *ExtractOpts) {
+		config.MaxEntries = entries
+	}
+}
+
+func ExtractPreserveModes(value bool) ExtractOptsFunc {
+	return func(config *ExtractOpts) {
+		config.PreserveModes = value
+	}
+}
+
+func ExtractPreserveTimes(value bool) ExtractOptsFunc {
+	return func(config *ExtractOpts) {
+		config.PreserveTimes = value
+	}
+}
+
+// ArchiveEntryError is returned by Path.ExtractFrom when an entry of the archive
+// could not be extracted.
+type ArchiveEntryError struct {
+	// Name is the name of the offending entry, as it appears in the archive.
+	Name string
+	Err  error
+}
+
+func (e *ArchiveEntryError) Error() string {
+	return fmt.Sprintf("archive entry %q: %v", e.Name, e.Err)
+}
+
+func (e *ArchiveEntryError) Unwrap() error {
+	return e.Err
+}
+
+// extractor writes the entries of an archive beneath its destination.
+type extractor struct {
+	dest *Path
+	// jail confines every write to the destination, including writes through
+	// symlinks that were extracted earlier.
+	jail    *jailFs
+	opts    *ExtractOpts
+	entries int
+	size    int64
+	// dirTimes are the modification times of the extracted directories, which
+	// are applied once all of their contents have been extracted.
+	dirTimes map[string]time.Time
+	// symlinks are the names of the extracted symlinks, as they appear in the
+	// archive, by their cleaned names.
+	symlinks map[string]string
+}
+
+// extractedName validates the name of an entry and returns it cleaned. An empty
+// name refers to the destination itself.
+func extractedName(name string) (string, error) {
+	if strings.Contains(name, "\\") || path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: absolute name", ErrArchiveEntryUnsafe)
+	}
+	for _, component := range strings.Split(name, "/") {
+		if component == ".." {
+			return "", fmt.Errorf("%w: name contains \"..\"", ErrArchiveEntryUnsafe)
+		}
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return "", nil
+	}
+	return cleaned, nil
+}
+
+// target returns the path in the destination that the cleaned name refers to.
+func (e *extractor) target(name string) *Path {
+	if name == "" {
+		return e.dest
+	}
+	return e.dest.Join(strings.Split(name, "/")...)
+}
+
+// begin counts an entry, and validates and cleans its name.
+func (e *extractor) begin(name string) (string, error) {
+	e.entries++
+	if e.opts.MaxEntries >= 0 && e.entries > e.opts.MaxEntries {
+		return "", fmt.Errorf("%w: more than %d entries", ErrArchiveLimitExceeded, e.opts.MaxEntries)
+	}
+	return extractedName(name)
+}
+
+// createParent creates the parent directories of target, as archives don't
+// necessarily contain entries for them.
+func (e *extractor) createParent(target *Path) error {
+	return e.jail.MkdirAll(target.Parent().String(), e.dest.DefaultDirMode)
+}
+
+func (e *extractor) dir(name string, mode os.FileMode, modTime time.Time) error {
+	cleaned, err := e.begin(name)
+	if err != nil {
+		return err
+	}
+	target := e.target(cleaned)
+	if err := e.jail.MkdirAll(target.String(), e.dest.DefaultDirMode); err != nil {
+		return err
+	}
+	if cleaned == "" {
+		return nil
+	}
+	if e.opts.PreserveModes {
+		if err := e.jail.Chmod(target.String(), mode.Perm()); err != nil {
+			return err
+		}
+	}
+	if e.opts.PreserveTimes {
+		e.dirTimes[target.String()] = modTime
+	}
+	return nil
+}
+
+func (e *extractor) file(name string, mode os.FileMode, modTime time.Time, r io.Reader) error {
+	cleaned, err := e.begin(name)
+	if err != nil {
+		return err
+	}
+	if cleaned == "" {
+		return fmt.Errorf("%w: file refers to the destination", ErrArchiveEntryUnsafe)
+	}
+	target := e.target(cleaned)
+	if err := e.createParent(target); err != nil {
+		return err
+	}
+	perm := e.dest.DefaultFileMode
+	if e.opts.PreserveModes {
+		perm = mode.Perm()
+	}
+	file, err := e.jail.OpenFile(target.String(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	var written int64
+	if e.opts.MaxTotalSize < 0 {
+		written, err = io.Copy(file, r)
+	} else {
+		// Copy one more byte than allowed to detect that the limit is exceeded.
+		written, err = io.CopyN(file, r, e.opts.MaxTotalSize-e.size+1)
+		if err == nil {
+			err = fmt.Errorf("%w: more than %d bytes", ErrArchiveLimitExceeded, e.opts.MaxTotalSize)
+		} else if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	e.size += written
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if e.opts.PreserveModes {
+		// The mode given to OpenFile is subject to the umask, and doesn't
+		// apply to existing files.
+		if err := e.jail.Chmod(target.String(), perm); err != nil {
+			return err
+		}
+	}
+	if e.opts.PreserveTimes {
+		return e.jail.Chtimes(target.String(), modTime, modTime)
+	}
+	return nil
+}
+
+func (e *extractor) symlink(name string, linkTarget string) error {
+	cleaned, err := e.begin(name)
+	if err != nil {
+		return err
+	}
+	if cleaned == "" {
+		return fmt.Errorf("%w: symlink refers to the destination", ErrArchiveEntryUnsafe)
+	}
+	if path.IsAbs(linkTarget) || filepath.IsAbs(linkTarget) || strings.Contains(linkTarget, "\\") {
+		return fmt.Errorf("%w: symlink to absolute path %q", ErrArchiveEntryUnsafe, linkTarget)
+	}
+	// The target is relative to the directory the symlink ends up in, which
+	// may differ from its parent in the archive if that's reached through
+	// symlinks extracted before it. The target itself may also go through
+	// such symlinks, so it's resolved rather than cleaned.
+	parent, err := secureResolve(e.jail.fs, e.jail.root, path.Dir(cleaned), true, false)
+	if errors.Is(err, ErrPathEscapesRoot) {
+		return fmt.Errorf("%w: symlink is created through a symlink outside of the destination", ErrArchiveEntryUnsafe)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = secureResolve(e.jail.fs, e.jail.root, parent+"/"+linkTarget, true, false)
+	if errors.Is(err, ErrPathEscapesRoot) {
+		return fmt.Errorf("%w: symlink to %q points outside of the destination", ErrArchiveEntryUnsafe, linkTarget)
+	}
+	if err != nil {
+		return err
+	}
+	target := e.target(cleaned)
+	if err := e.createParent(target); err != nil {
+		return err
+	}
+	if err := e.jail.SymlinkIfPossible(linkTarget, target.String()); err != nil {
+		return err
+	}
+	e.symlinks[cleaned] = name
+	return nil
+}
+
+// finish checks the extracted symlinks once more, as a symlink extracted later
+// may change where the target of an earlier one resolves to, for instance "p"
+// pointing at "q/.." followed by "q" pointing at ".". Symlinks that now point
+// outside of the destination are removed. Finally, the modification times of the
+// directories are applied.
+func (e *extractor) finish() error {
+	var unsafe *ArchiveEntryError
+	for cleaned, name := range e.symlinks {
+		_, err := secureResolve(e.jail.fs, e.jail.root, cleaned, true, false)
+		if !errors.Is(err, ErrPathEscapesRoot) {
+			continue
+		}
+		if err := e.jail.Remove(e.target(cleaned).String()); err != nil {
+			return err
+		}
+		// Report the first one by name, as the map is iterated in random order.
+		if unsafe == nil || name < unsafe.Name {
+			err := fmt.Errorf("%w: symlink points outside of the destination through a symlink extracted after it", ErrArchiveEntryUnsafe)
+			unsafe = &ArchiveEntryError{Name: name, Err: err}
+		}
+	}
+	if unsafe != nil {
+		return unsafe
+	}
+	for dir, modTime := range e.dirTimes {
+		if err := e.jail.Chtimes(dir, modTime, modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractFrom extracts the archive read from r, in the given format, beneath the
+// path, which is created if it doesn't exist. Entries with absolute names or names
+// containing "..", and symlinks pointing outside of the path are rejected, and no
+// entry is ever written outside of the path, even through symlinks that exist
+// beforehand or were extracted from the archive. The limits of ExtractOpts guard
+// against archives that expand to excessive sizes.
+//
+// Zip archives are read into memory unless r implements io.ReaderAt and
+// io.Seeker. Errors concerning a specific entry are returned as an
+// *ArchiveEntryError. Extraction stops at the first error, leaving the entries
+// extracted so far in place.
+func (p *Path) ExtractFrom(r io.Reader, format ArchiveFormat, opts ...ExtractOptsFunc) error {
+	config := DefaultExtractOpts()
+	for _, opt := range opts {
+		opt(config)
+	}
+	if err := p.MkdirAll(); err != nil {
+		return err
+	}
+	e := &extractor{
+		dest:     p,
+		jail:     &jailFs{fs: p.Fs(), root: filepath.Clean(p.String())},
+		opts:     config,
+		dirTimes: map[string]time.Time{},
+		symlinks: map[string]string{},
+	}
+
+	var err error
+	switch format {
+	case ArchiveTar:
+		err = e.extractTar(r)
+	case ArchiveTarGz:
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(r)
+		if err == nil {
+			err = e.extractTar(gz)
+		}
+	case ArchiveZip:
+		err = e.extractZip(r)
+	default:
+		err = fmt.Errorf("unknown archive format %s", format)
+	}
+	if err != nil {
+		return err
+	}
+	return e.finish()
+}
+
+func (e *extractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := e.extractTarEntry(tr, header); err != nil {
+			return &ArchiveEntryError{Name: header.Name, Err: err}
+		}
+	}
+}
+
+func (e *extractor) extractTarEntry(tr *tar.Reader, header *tar.Header) error {
+	mode := header.FileInfo().Mode()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return e.dir(header.Name, mode, header.ModTime)
+	case tar.TypeReg:
+		return e.file(header.Name, mode, header.ModTime, tr)
+	case tar.TypeSymlink:
+		return e.symlink(header.Name, header.Linkname)
+	case tar.TypeLink:
+		// Hard links are extracted as copies of the file they link to, which
+		// must have been extracted already.
+		linked, err := extractedName(header.Linkname)
+		if err != nil {
+			return err
+		}
+		source, err := e.jail.Open(e.target(linked).String())
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		return e.file(header.Name, mode, header.ModTime, source)
+	case tar.TypeXGlobalHeader:
+		return nil
+	default:
+		return fmt.Errorf("unsupported entry type %q", header.Typeflag)
+	}
+}
+
+// zipEntryOverhead is the number of bytes that is allowed for the headers of
+// each entry of a zip archive that has to be buffered in memory.
+const zipEntryOverhead = 1 << 10
+
+// zipBufferLimit returns the maximum size of a zip archive that has to be
+// buffered in memory, or -1 if there is no limit.
+func (o *ExtractOpts) zipBufferLimit() int64 {
+	if o.MaxTotalSize < 0 || o.MaxEntries < 0 {
+		return -1
+	}
+	return o.MaxTotalSize + int64(o.MaxEntries)*zipEntryOverhead
+}
+
+func (e *extractor) extractZip(r io.Reader) error {
+	var readerAt io.ReaderAt
+	var size int64
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		if ra, ok := r.(io.ReaderAt); ok {
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err != nil {
+				return err
+			}
+			readerAt, size = ra, end
+		}
+	}
+	if readerAt == nil {
+		// The central directory is at the end of the archive, so it has to be
+		// buffered in memory. Compressing entries never makes them much larger,
+		// so an archive within the limits can't be much larger than them either.
+		limit := e.opts.zipBufferLimit()
+		if limit >= 0 {
+			r = io.LimitReader(r, limit+1)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if limit >= 0 && int64(len(data)) > limit {
+			return fmt.Errorf("%w: zip archive of more than %d bytes", ErrArchiveLimitExceeded, limit)
+		}
+		readerAt, size = bytes.NewReader(data), int64(len(data))
+	}
+	zr, err := zip.NewReader(readerAt, size)
+	if err != nil {
+		return err
+	}
+	for _, file := range zr.File {
+		if err := e.extractZipEntry(file); err != nil {
+			return &ArchiveEntryError{Name: file.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// maxSymlinkTargetSize is the maximum size of a symlink target in a zip archive.
+const maxSymlinkTargetSize = 4096
+
+func (e *extractor) extractZipEntry(file *zip.File) error {
+	mode := file.Mode()
+	if !IsFile(mode) && !IsDir(mode) && !IsSymlink(mode) {
+		return fmt.Errorf("unsupported %s entry", FileTypeOf(mode))
+	}
+	if IsDir(mode) {
+		return e.dir(file.Name, mode, file.Modified)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if IsSymlink(mode) {
+		linkTarget, err := io.ReadAll(io.LimitReader(rc, maxSymlinkTargetSize+1))
+		if err != nil {
+			return err
+		}
+		if len(linkTarget) > maxSymlinkTargetSize {
+			return fmt.Errorf("%w: symlink target is too long", ErrArchiveEntryUnsafe)
+		}
+		return e.symlink(file.Name, string(linkTarget))
+	}
+	return e.file(file.Name, mode, file.Modified, rc)
+}
@@ -0,0 +1,231 @@
+package pathlib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarEntry describes an entry written by newTar.
+type tarEntry struct {
+	header   tar.Header
+	contents string
+}
+
+func newTar(t *testing.T, entries ...tarEntry) []byte {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, entry := range entries {
+		header := entry.header
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(entry.contents))
+		}
+		if header.Mode == 0 {
+			header.Mode = 0o644
+		}
+		require.NoError(t, tw.WriteHeader(&header))
+		_, err := tw.Write([]byte(entry.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func tarRegular(name string, contents string) tarEntry {
+	return tarEntry{tar.Header{Name: name, Typeflag: tar.TypeReg}, contents}
+}
+
+func tarSymlink(name string, target string) tarEntry {
+	return tarEntry{tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: target}, ""}
+}
+
+func TestExtractFromRoundTrip(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveTarGz, ArchiveZip} {
+		t.Run(format.String(), func(t *testing.T) {
+			src := NewPath("/src", PathWithAfero(afero.NewMemMapFs()))
+			newArchiveTree(t, src)
+			modTime := time.Date(2020, time.February, 3, 4, 5, 6, 0, time.UTC)
+			require.NoError(t, src.Join("dir", "sub").Chtimes(modTime, modTime))
+			buf := &bytes.Buffer{}
+			require.NoError(t, src.ArchiveTo(buf, format))
+
+			dest := NewPath("/dest", PathWithAfero(afero.NewMemMapFs()))
+			require.NoError(t, dest.ExtractFrom(buf, format, ExtractPreserveModes(true), ExtractPreserveTimes(true)))
+
+			for name, contents := range map[string]string{"a.txt": "a", "dir/b.txt": "bb", "dir/sub/c.txt": "ccc"} {
+				extracted, err := dest.Join(name).ReadFile()
+				require.NoError(t, err, name)
+				assert.Equal(t, contents, string(extracted), name)
+			}
+			info, err := dest.Join("a.txt").Stat()
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+			info, err = dest.Join("dir", "sub").Stat()
+			require.NoError(t, err)
+			assert.True(t, modTime.Equal(info.ModTime()), info.ModTime())
+		})
+	}
+}
+
+func TestExtractFromUnsafe(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		entries []tarEntry
+		bad     string
+	}{
+		{"parent", []tarEntry{tarRegular("ok.txt", ""), tarRegular("../evil.txt", "evil")}, "../evil.txt"},
+		{"nested parent", []tarEntry{tarRegular("a/../../evil.txt", "evil")}, "a/../../evil.txt"},
+		{"absolute", []tarEntry{tarRegular("/evil.txt", "evil")}, "/evil.txt"},
+		{"backslash", []tarEntry{tarRegular("..\\evil.txt", "evil")}, "..\\evil.txt"},
+		{"symlink outside", []tarEntry{tarSymlink("dir/link", "../../evil")}, "dir/link"},
+		{"absolute symlink", []tarEntry{tarSymlink("link", "/etc/passwd")}, "link"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			dest := NewPath("/root/dest", PathWithAfero(fs))
+			err := dest.ExtractFrom(bytes.NewReader(newTar(t, tt.entries...)), ArchiveTar)
+			require.Error(t, err)
+			var entryErr *ArchiveEntryError
+			require.True(t, errors.As(err, &entryErr), err)
+			assert.Equal(t, tt.bad, entryErr.Name)
+			assert.True(t, errors.Is(err, ErrArchiveEntryUnsafe), err)
+
+			exists, err := afero.Exists(fs, "/root/evil.txt")
+			require.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestExtractFromThroughSymlink(t *testing.T) {
+	parent := NewPath(t.TempDir())
+	dest := parent.Join("dest")
+	require.NoError(t, dest.MkdirAll())
+
+	// Symlinks pointing inside of the destination are extracted, and may be
+	// written through.
+	archive := newTar(t, tarRegular("dir/file.txt", "file"), tarSymlink("link", "dir"), tarRegular("link/other.txt", "other"))
+	require.NoError(t, dest.ExtractFrom(bytes.NewReader(archive), ArchiveTar))
+	contents, err := dest.Join("dir", "other.txt").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, "other", string(contents))
+
+	// A symlink that already exists is never written through if it points
+	// outside of the destination.
+	require.NoError(t, dest.Join("escape").SymlinkStr(parent.String()))
+	err = dest.ExtractFrom(bytes.NewReader(newTar(t, tarRegular("escape/evil.txt", "evil"))), ArchiveTar)
+	var entryErr *ArchiveEntryError
+	require.True(t, errors.As(err, &entryErr), err)
+	assert.Equal(t, "escape/evil.txt", entryErr.Name)
+	assert.True(t, errors.Is(err, ErrPathEscapesRoot), err)
+	exists, err := parent.Join("evil.txt").Exists()
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// A symlink created through a symlink from the same archive is checked
+	// against the directory it ends up in.
+	chained := parent.Join("chained")
+	require.NoError(t, chained.MkdirAll())
+	archive = newTar(t,
+		tarEntry{tar.Header{Name: "d/", Typeflag: tar.TypeDir, Mode: 0o755}, ""},
+		tarSymlink("d/l", ".."),
+		tarSymlink("d/l/x", "../../evil"),
+	)
+	err = chained.ExtractFrom(bytes.NewReader(archive), ArchiveTar)
+	require.True(t, errors.As(err, &entryErr), err)
+	assert.Equal(t, "d/l/x", entryErr.Name)
+	assert.True(t, errors.Is(err, ErrArchiveEntryUnsafe), err)
+	_, err = chained.Join("x").Lstat()
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+
+	// The target of a symlink is resolved through the symlinks extracted before
+	// it rather than cleaned, and checked again once symlinks extracted after it
+	// exist.
+	through := parent.Join("through")
+	require.NoError(t, through.MkdirAll())
+	archive = newTar(t, tarSymlink("q", "."), tarSymlink("p", "q/.."))
+	err = through.ExtractFrom(bytes.NewReader(archive), ArchiveTar)
+	require.True(t, errors.As(err, &entryErr), err)
+	assert.Equal(t, "p", entryErr.Name)
+	assert.True(t, errors.Is(err, ErrArchiveEntryUnsafe), err)
+	_, err = through.Join("p").Lstat()
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+
+	reversed := parent.Join("reversed")
+	require.NoError(t, reversed.MkdirAll())
+	archive = newTar(t, tarSymlink("p", "q/.."), tarSymlink("q", "."))
+	err = reversed.ExtractFrom(bytes.NewReader(archive), ArchiveTar)
+	require.True(t, errors.As(err, &entryErr), err)
+	assert.Equal(t, "p", entryErr.Name)
+	assert.True(t, errors.Is(err, ErrArchiveEntryUnsafe), err)
+	_, err = reversed.Join("p").Lstat()
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+}
+
+func TestExtractFromLimits(t *testing.T) {
+	dest := NewPath("/dest", PathWithAfero(afero.NewMemMapFs()))
+	archive := newTar(t, tarRegular("a.txt", "aaaaa"), tarRegular("b.txt", "bbbbb"), tarRegular("c.txt", "c"))
+
+	err := dest.ExtractFrom(bytes.NewReader(archive), ArchiveTar, ExtractMaxEntries(2))
+	var entryErr *ArchiveEntryError
+	require.True(t, errors.As(err, &entryErr), err)
+	assert.Equal(t, "c.txt", entryErr.Name)
+	assert.True(t, errors.Is(err, ErrArchiveLimitExceeded))
+
+	err = dest.ExtractFrom(bytes.NewReader(archive), ArchiveTar, ExtractMaxTotalSize(10))
+	require.True(t, errors.As(err, &entryErr), err)
+	assert.Equal(t, "c.txt", entryErr.Name)
+	assert.True(t, errors.Is(err, ErrArchiveLimitExceeded))
+
+	require.NoError(t, dest.ExtractFrom(bytes.NewReader(archive), ArchiveTar, ExtractMaxTotalSize(11), ExtractMaxEntries(-1)))
+
+	// A highly compressible zip entry is cut off at the limit.
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("bomb")
+	require.NoError(t, err)
+	_, err = w.Write(bytes.Repeat([]byte{0}, 10<<20))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	err = dest.ExtractFrom(bytes.NewReader(buf.Bytes()), ArchiveZip, ExtractMaxTotalSize(1<<20))
+	assert.True(t, errors.Is(err, ErrArchiveLimitExceeded))
+	info, err := dest.Join("bomb").Stat()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(1<<20+1))
+
+	// A zip archive read from a stream is buffered only up to the limit.
+	buf = &bytes.Buffer{}
+	zw = zip.NewWriter(buf)
+	w, err = zw.CreateHeader(&zip.FileHeader{Name: "stored", Method: zip.Store})
+	require.NoError(t, err)
+	_, err = w.Write(bytes.Repeat([]byte{1}, 10<<10))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	stream := struct{ io.Reader }{bytes.NewReader(buf.Bytes())}
+	err = dest.ExtractFrom(stream, ArchiveZip, ExtractMaxTotalSize(1<<10), ExtractMaxEntries(1))
+	assert.True(t, errors.Is(err, ErrArchiveLimitExceeded), err)
+	exists, err := dest.Join("stored").Exists()
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	stream = struct{ io.Reader }{bytes.NewReader(buf.Bytes())}
+	require.NoError(t, dest.ExtractFrom(stream, ArchiveZip, ExtractMaxTotalSize(10<<10), ExtractMaxEntries(1)))
+}
+
+func TestExtractFromUnsupported(t *testing.T) {
+	dest := NewPath("/dest", PathWithAfero(afero.NewMemMapFs()))
+	archive := newTar(t, tarEntry{tar.Header{Name: "fifo", Typeflag: tar.TypeFifo}, ""})
+	err := dest.ExtractFrom(bytes.NewReader(archive), ArchiveTar)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), `"fifo"`), err.Error())
+}
@@ -0,0 +1,91 @@
+package pathlib
+
+import (
+	"fmt"
+	"os"
+)
+
+// FileType classifies filesystem objects by the type bits of their os.FileMode.
+type FileType int
+
+const (
+	// FileTypeRegular is a regular file.
+	FileTypeRegular FileType = iota
+	// FileTypeDir is a directory.
+	FileTypeDir
+	// FileTypeSymlink is a symbolic link.
+	FileTypeSymlink
+	// FileTypeFIFO is a named pipe.
+	FileTypeFIFO
+	// FileTypeSocket is a Unix domain socket.
+	FileTypeSocket
+	// FileTypeBlockDevice is a block device.
+	FileTypeBlockDevice
+	// FileTypeCharDevice is a character device.
+	FileTypeCharDevice
+	// FileTypeOther is any other type of object, such as those reported with
+	// os.ModeIrregular.
+	FileTypeOther
+)
+
+// String returns a human readable name of the file type.
+func (t FileType) String() string {
+	switch t {
+	case FileTypeRegular:
+		return "regular file"
+	case FileTypeDir:
+		return "directory"
+	case FileTypeSymlink:
+		return "symlink"
+	case FileTypeFIFO:
+		return "fifo"
+	case FileTypeSocket:
+		return "socket"
+	case FileTypeBlockDevice:
+		return "block device"
+	case FileTypeCharDevice:
+		return "character device"
+	default:
+		return "other"
+	}
+}
+
+// FileTypeOf returns the type of the object described by the given os.FileMode.
+func FileTypeOf(mode os.FileMode) FileType {
+	switch {
+	case IsFile(mode):
+		return FileTypeRegular
+	case IsDir(mode):
+		return FileTypeDir
+	case IsSymlink(mode):
+		return FileTypeSymlink
+	case IsFIFO(mode):
+		return FileTypeFIFO
+	case IsSocket(mode):
+		return FileTypeSocket
+	case IsCharDevice(mode):
+		return FileTypeCharDevice
+	case IsDevice(mode):
+		return FileTypeBlockDevice
+	default:
+		return FileTypeOther
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, encoding the file type as its
+// String.
+func (t FileType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding a file type that
+// was encoded by MarshalText.
+func (t *FileType) UnmarshalText(text []byte) error {
+	for fileType := FileTypeRegular; fileType <= FileTypeOther; fileType++ {
+		if fileType.String() == string(text) {
+			*t = fileType
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown file type %q", text)
+}
@@ -0,0 +1,106 @@
+package pathlib
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTypeOf(t *testing.T) {
+	for _, tt := range []struct {
+		mode     os.FileMode
+		expected FileType
+	}{
+		{0o644, FileTypeRegular},
+		{os.ModeDir | 0o755, FileTypeDir},
+		{os.ModeSymlink | 0o777, FileTypeSymlink},
+		{os.ModeNamedPipe | 0o600, FileTypeFIFO},
+		{os.ModeSocket | 0o755, FileTypeSocket},
+		{os.ModeDevice | 0o660, FileTypeBlockDevice},
+		{os.ModeDevice | os.ModeCharDevice | 0o666, FileTypeCharDevice},
+		{os.ModeIrregular, FileTypeOther},
+	} {
+		t.Run(tt.expected.String(), func(t *testing.T) {
+			assert.Equal(t, tt.expected, FileTypeOf(tt.mode))
+		})
+	}
+}
+
+func TestFileTypeText(t *testing.T) {
+	for fileType := FileTypeRegular; fileType <= FileTypeOther; fileType++ {
+		text, err := fileType.MarshalText()
+		require.NoError(t, err)
+		var decoded FileType
+		require.NoError(t, decoded.UnmarshalText(text))
+		assert.Equal(t, fileType, decoded)
+	}
+	var decoded FileType
+	assert.Error(t, decoded.UnmarshalText([]byte("unknown")))
+}
+
+// listenUnix creates a Unix domain socket at path, skipping the test if the
+// platform doesn't support them.
+func listenUnix(t *testing.T, path *Path) {
+	listener, err := net.Listen("unix", path.String())
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+}
+
+func TestPathFileType(t *testing.T) {
+	root := NewPath(t.TempDir())
+	file := root.Join("file.txt")
+	require.NoError(t, file.WriteFile([]byte("")))
+	symlink := root.Join("symlink")
+	require.NoError(t, symlink.Symlink(file))
+
+	for path, expected := range map[*Path]FileType{
+		root:    FileTypeDir,
+		file:    FileTypeRegular,
+		symlink: FileTypeSymlink,
+	} {
+		fileType, err := path.FileType()
+		require.NoError(t, err)
+		assert.Equal(t, expected, fileType, path.String())
+	}
+
+	_, err := root.Join("missing").FileType()
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPathIsSocket(t *testing.T) {
+	socket := NewPath(t.TempDir()).Join("sock")
+	listenUnix(t, socket)
+
+	isSocket, err := socket.IsSocket()
+	require.NoError(t, err)
+	assert.True(t, isSocket)
+	isFIFO, err := socket.IsFIFO()
+	require.NoError(t, err)
+	assert.False(t, isFIFO)
+	fileType, err := socket.FileType()
+	require.NoError(t, err)
+	assert.Equal(t, FileTypeSocket, fileType)
+}
+
+func TestPathIsCharDevice(t *testing.T) {
+	devNull := NewPath(os.DevNull)
+	info, err := devNull.Stat()
+	if err != nil || !IsDevice(info.Mode()) {
+		t.Skipf("%s is not a device on this platform", os.DevNull)
+	}
+
+	isDevice, err := devNull.IsDevice()
+	require.NoError(t, err)
+	assert.True(t, isDevice)
+	isCharDevice, err := devNull.IsCharDevice()
+	require.NoError(t, err)
+	assert.True(t, isCharDevice)
+	fileType, err := devNull.FileType()
+	require.NoError(t, err)
+	assert.Equal(t, FileTypeCharDevice, fileType)
+}
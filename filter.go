@@ -0,0 +1,116 @@
+package pathlib
+
+import (
+	"os"
+	"regexp"
+	"time"
+)
+
+// FilterFunc reports whether the object at path, described by info, should be
+// visited during a walk. FilterFuncs can be combined with FilterAnd, FilterOr and
+// FilterNot.
+type FilterFunc func(path *Path, info os.FileInfo) bool
+
+// FilterAnd returns a FilterFunc that passes if all of the filters pass. An empty
+// list of filters always passes.
+func FilterAnd(filters ...FilterFunc) FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		for _, filter := range filters {
+			if !filter(path, info) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// FilterOr returns a FilterFunc that passes if any of the filters pass. An empty
+// list of filters never passes.
+func FilterOr(filters ...FilterFunc) FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		for _, filter := range filters {
+			if filter(path, info) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterNot returns a FilterFunc that passes if filter does not.
+func FilterNot(filter FilterFunc) FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		return !filter(path, info)
+	}
+}
+
+// FilterModifiedBefore passes objects whose mtime is before t.
+func FilterModifiedBefore(t time.Time) FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		return info.ModTime().Before(t)
+	}
+}
+
+// FilterModifiedAfter passes objects whose mtime is after t.
+func FilterModifiedAfter(t time.Time) FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		return info.ModTime().After(t)
+	}
+}
+
+// FilterNameMatches passes objects whose name, as returned by Path.Name(),
+// matches the regular expression.
+func FilterNameMatches(re *regexp.Regexp) FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		return re.MatchString(path.Name())
+	}
+}
+
+// FilterMode passes objects whose permission bits include all of the bits in perm.
+func FilterMode(perm os.FileMode) FilterFunc {
+	perm &= os.ModePerm
+	return func(path *Path, info os.FileInfo) bool {
+		return info.Mode()&perm == perm
+	}
+}
+
+// FilterExecutable passes regular files that are executable by their owner, group,
+// or others.
+func FilterExecutable() FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		return IsFile(info.Mode()) && info.Mode()&0o111 != 0
+	}
+}
+
+// FilterOwner passes objects owned by the given user ID. Objects on filesystems
+// that don't expose ownership information never pass.
+func FilterOwner(uid int) FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		owner, _, ok := fileOwner(info)
+		return ok && owner == uid
+	}
+}
+
+// FilterGroup passes objects owned by the given group ID. Objects on filesystems
+// that don't expose ownership information never pass.
+func FilterGroup(gid int) FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		_, group, ok := fileOwner(info)
+		return ok && group == gid
+	}
+}
+
+// FilterEmpty passes regular files with a size of zero and directories with no
+// children. Directories that can't be read do not pass.
+func FilterEmpty() FilterFunc {
+	return func(path *Path, info os.FileInfo) bool {
+		if IsFile(info.Mode()) {
+			return info.Size() == 0
+		}
+		if IsDir(info.Mode()) {
+			empty, err := path.IsEmpty()
+			return err == nil && empty
+		}
+		return false
+	}
+}
@@ -0,0 +1,95 @@
+package pathlib
+
+import (
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilters(t *testing.T) {
+	root := NewPath(t.TempDir())
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	script := root.Join("script.sh")
+	require.NoError(t, script.WriteFileMode([]byte("#!/bin/sh"), 0o755))
+	empty := root.Join("empty.txt")
+	require.NoError(t, empty.WriteFile([]byte{}))
+	require.NoError(t, empty.Chtimes(old, old))
+	emptyDir := root.Join("emptydir")
+	require.NoError(t, emptyDir.Mkdir())
+	fullDir := root.Join("fulldir")
+	require.NoError(t, fullDir.Mkdir())
+	require.NoError(t, fullDir.Join("file2.txt").WriteFile([]byte("hello")))
+
+	for _, tt := range []struct {
+		name   string
+		filter FilterFunc
+		want   []*Path
+	}{
+		{"modified before", FilterModifiedBefore(old.Add(time.Hour)), []*Path{empty}},
+		{"modified after", FilterModifiedAfter(old.Add(time.Hour)), []*Path{script, emptyDir, fullDir}},
+		{"name regex", FilterNameMatches(regexp.MustCompile(`^[a-z]+\.txt$`)), []*Path{empty}},
+		{"mode", FilterMode(0o700), []*Path{script, emptyDir, fullDir}},
+		{"executable", FilterExecutable(), []*Path{script}},
+		{"empty", FilterEmpty(), []*Path{empty, emptyDir}},
+		{"not empty", FilterNot(FilterEmpty()), []*Path{script, fullDir}},
+		{"and", FilterAnd(FilterEmpty(), FilterNameMatches(regexp.MustCompile(`dir`))), []*Path{emptyDir}},
+		{"or", FilterOr(FilterExecutable(), FilterNameMatches(regexp.MustCompile(`^full`))), []*Path{script, fullDir}},
+		{"empty and", FilterAnd(), []*Path{script, empty, emptyDir, fullDir}},
+		{"empty or", FilterOr(), []*Path{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []*Path{}
+			for _, p := range []*Path{script, empty, emptyDir, fullDir} {
+				info, err := p.Lstat()
+				require.NoError(t, err)
+				if tt.filter(p, info) {
+					got = append(got, p)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFilterOwner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ownership is not supported on windows")
+	}
+	file := NewPath(t.TempDir()).Join("file.txt")
+	require.NoError(t, file.WriteFile([]byte("")))
+	info, err := file.Stat()
+	require.NoError(t, err)
+
+	assert.True(t, FilterOwner(os.Getuid())(file, info))
+	assert.False(t, FilterOwner(os.Getuid()+1)(file, info))
+	assert.True(t, FilterGroup(os.Getgid())(file, info))
+	assert.False(t, FilterGroup(os.Getgid()+1)(file, info))
+}
+
+func TestWalkFilter(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+
+	walker, err := NewWalk(
+		root,
+		WalkFilter(FilterNameMatches(regexp.MustCompile(`^file1`))),
+		WalkFilter(FilterNot(FilterEmpty())),
+	)
+	require.NoError(t, err)
+	visited := []string{}
+	require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		rel, err := path.RelativeTo(root)
+		require.NoError(t, err)
+		visited = append(visited, rel.String())
+		return nil
+	}))
+	slices.Sort(visited)
+	assert.Equal(t, []string{"file1.txt", "subdir/file1.txt"}, visited)
+}
@@ -0,0 +1,83 @@
+package pathlib
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// matchPattern reports whether name matches the shell pattern. Both
+// pattern and name are "/"-separated. The pattern syntax is the same as
+// path.Match, with the addition that a path component consisting solely of
+// "**" matches zero or more path components. A pattern that contains no
+// "/" is matched against the final component of name only, so that
+// "node_modules" matches "node_modules", "a/node_modules", etc.
+func matchPattern(pattern string, name string) (bool, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if !strings.Contains(pattern, "/") {
+		if idx := strings.LastIndex(name, "/"); idx >= 0 {
+			name = name[idx+1:]
+		}
+		return path.Match(pattern, name)
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+	return matchComponents(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchComponents(pattern []string, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive "**" components, as they are equivalent
+			// to a single one.
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				matched, err := matchComponents(pattern, name[i:])
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		matched, err := path.Match(pattern[0], name[0])
+		if err != nil || !matched {
+			return false, err
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// matchAnyPattern returns whether name matches any of the given patterns.
+func matchAnyPattern(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := matchPattern(pattern, name)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// validatePatterns returns an error if any of the patterns are malformed.
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		for _, component := range strings.Split(pattern, "/") {
+			if _, err := path.Match(component, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
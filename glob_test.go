@@ -0,0 +1,43 @@
+package pathlib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPattern(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", true},
+		{"*.go", "main.txt", false},
+		{"node_modules", "a/b/node_modules", true},
+		{"pkg/*.go", "pkg/main.go", true},
+		{"pkg/*.go", "pkg/sub/main.go", false},
+		{"pkg/*.go", "other/pkg/main.go", false},
+		{"/pkg/*.go", "pkg/main.go", true},
+		{"pkg/**/*.go", "pkg/main.go", true},
+		{"pkg/**/*.go", "pkg/a/b/main.go", true},
+		{"**/vendor/**", "a/vendor/b/c", true},
+		{"**/vendor/**", "vendor", true},
+		{"**/vendor/**", "vendored/b", false},
+		{"a/**/**/b", "a/b", true},
+		{"a/**", "ab/c", false},
+	} {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			got, err := matchPattern(tt.pattern, tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	assert.NoError(t, validatePatterns([]string{"**/*.go", "[a-z]"}))
+	assert.Error(t, validatePatterns([]string{"foo/[", "*.go"}))
+}
@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/spf13/afero v1.4.0
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/sys v0.30.0
 )
 
 require (
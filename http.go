@@ -0,0 +1,286 @@
+package pathlib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpHandler is the http.Handler returned by Path.HTTPHandler.
+type httpHandler struct {
+	walk *Walk
+	// ignore is the matcher for the IgnoreFiles of the walk.
+	ignore *IgnoreMatcher
+	// ignoreMu guards the rules cached by ignore, as requests are served
+	// concurrently.
+	ignoreMu sync.Mutex
+}
+
+// httpDirEntry is the JSON representation of an object in a directory listing.
+type httpDirEntry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"modTime"`
+	IsDir   bool      `json:"isDir"`
+}
+
+// HTTPHandler returns an http.Handler that serves the tree rooted at the path.
+// GET and HEAD requests for files are served with http.ServeContent, which
+// handles range and conditional requests, using an ETag derived from the file's
+// modification time and size. Requests for directories are answered with a
+// listing of their children, as HTML or, if the request accepts
+// "application/json" or has a "format=json" query parameter, as a JSON array of
+// objects with the keys "name", "size", "mode", "modTime" and "isDir".
+//
+// opts select the objects that are served, with the same semantics as for a
+// Walk: Include, Exclude, Filters and the Visit* options determine which
+// non-directories are served, while directories are always served unless they
+// are pruned with PruneDirs or ignored through IgnoreFiles, or lie deeper than
+// Depth. Requests can't escape the root using "..", and symlinks are only served
+// with FollowSymlinks, in which case they may point outside of the tree.
+func (p *Path) HTTPHandler(opts ...WalkOptsFunc) (http.Handler, error) {
+	walk, err := NewWalk(p, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, patterns := range [][]string{walk.Opts.Include, walk.Opts.Exclude, walk.Opts.PruneDirs} {
+		if err := validatePatterns(patterns); err != nil {
+			return nil, err
+		}
+	}
+	handler := &httpHandler{walk: walk}
+	if len(walk.Opts.IgnoreFiles) != 0 {
+		handler.ignore = NewIgnoreMatcher(p, walk.Opts.IgnoreFiles...)
+	}
+	return handler, nil
+}
+
+// child returns the entry of the child of dir with the given name, or nil if the
+// child is not served.
+func (h *httpHandler) child(dir *WalkEntry, name string) (*WalkEntry, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "\\\x00") {
+		return nil, nil
+	}
+	depth := dir.Depth + 1
+	if h.walk.maxDepthReached(depth) {
+		return nil, nil
+	}
+	relative := name
+	if dir.relative != "" {
+		relative = dir.relative + "/" + name
+	}
+	child := dir.Path.Join(name)
+	info, err := lstatIfPossible(child)
+	if err != nil {
+		return nil, err
+	}
+	if IsSymlink(info.Mode()) {
+		if !h.walk.Opts.FollowSymlinks {
+			return nil, nil
+		}
+		if info, err = child.Stat(); err != nil {
+			return nil, err
+		}
+	}
+	if info.IsDir() {
+		pruned, err := matchAnyPattern(h.walk.Opts.PruneDirs, relative)
+		if err != nil || pruned {
+			return nil, err
+		}
+	}
+	if h.ignore != nil {
+		h.ignoreMu.Lock()
+		ignored, err := h.ignore.matchesRules(relative, info.IsDir())
+		h.ignoreMu.Unlock()
+		if err != nil || ignored {
+			return nil, err
+		}
+	}
+	passesPatterns, err := h.walk.Opts.passesPatterns(relative)
+	if err != nil {
+		return nil, err
+	}
+	entry := &WalkEntry{
+		Path:           child,
+		Relative:       NewPathAfero(relative, child.Fs()),
+		Depth:          depth,
+		Info:           info,
+		Parent:         dir,
+		relative:       relative,
+		passesPatterns: passesPatterns,
+	}
+	if !info.IsDir() {
+		served, err := h.walk.passesFilters(entry)
+		if err != nil || !served {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// lookup returns the entry of the object at the given "/"-separated path relative
+// to the root, or nil if the object is not served.
+func (h *httpHandler) lookup(relative string) (*WalkEntry, error) {
+	info, err := h.walk.root.Stat()
+	if err != nil {
+		return nil, err
+	}
+	entry := &WalkEntry{
+		Path:           h.walk.root,
+		Relative:       NewPathAfero(".", h.walk.root.Fs()),
+		Depth:          -1,
+		Info:           info,
+		passesPatterns: true,
+	}
+	if relative == "" {
+		return entry, nil
+	}
+	for _, name := range strings.Split(relative, "/") {
+		if !entry.Info.IsDir() {
+			return nil, nil
+		}
+		entry, err = h.child(entry, name)
+		if err != nil || entry == nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	// Cleaning the rooted path removes any ".." that would escape the root.
+	name := path.Clean("/" + r.URL.Path)
+	entry, err := h.lookup(strings.TrimPrefix(name, "/"))
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if entry == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if entry.Info.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") && name != "/" {
+			httpRedirect(w, r, path.Base(name)+"/")
+			return
+		}
+		h.serveDir(w, r, name, entry)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/") {
+		httpRedirect(w, r, "../"+path.Base(name))
+		return
+	}
+
+	file, err := entry.Path.Open()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Etag", fmt.Sprintf(`"%x-%x"`, entry.Info.ModTime().UnixNano(), entry.Info.Size()))
+	http.ServeContent(w, r, entry.Path.Name(), entry.Info.ModTime(), file)
+}
+
+// serveDir writes the listing of the directory described by entry.
+func (h *httpHandler) serveDir(w http.ResponseWriter, r *http.Request, name string, entry *WalkEntry) {
+	children, err := entry.Path.ReadDir()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	listing := []httpDirEntry{}
+	for _, child := range children {
+		childEntry, err := h.child(entry, child.Name())
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// The child was removed since the directory was read.
+				continue
+			}
+			httpError(w, err)
+			return
+		}
+		if childEntry == nil {
+			continue
+		}
+		listing = append(listing, httpDirEntry{
+			Name:    child.Name(),
+			Size:    childEntry.Info.Size(),
+			Mode:    childEntry.Info.Mode().String(),
+			ModTime: childEntry.Info.ModTime(),
+			IsDir:   childEntry.Info.IsDir(),
+		})
+	}
+	slices.SortFunc(listing, func(a, b httpDirEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(listing); err != nil {
+			httpError(w, err)
+		}
+		return
+	}
+
+	if name != "/" {
+		name += "/"
+	}
+	var b strings.Builder
+	title := html.EscapeString("Index of " + name)
+	fmt.Fprintf(&b, "<!doctype html>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<h1>%s</h1>\n<ul>\n", title, title)
+	if name != "/" {
+		b.WriteString("<li><a href=\"../\">../</a></li>\n")
+	}
+	for _, child := range listing {
+		display := child.Name
+		if child.IsDir {
+			display += "/"
+		}
+		// Escaping the name as a URL path keeps names containing ":" from being
+		// interpreted as a scheme.
+		href := (&url.URL{Path: "./" + display}).String()
+		fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(href), html.EscapeString(display))
+	}
+	b.WriteString("</ul>\n")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+// httpRedirect redirects the request to target, which is relative to the
+// request's path, preserving the query.
+func httpRedirect(w http.ResponseWriter, r *http.Request, target string) {
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// httpError responds to the request with the status code that corresponds to err.
+func httpError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
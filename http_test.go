@@ -0,0 +1,174 @@
+package pathlib
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type HTTPHandlerSuite struct {
+	suite.Suite
+	root    *Path
+	handler http.Handler
+}
+
+func (s *HTTPHandlerSuite) SetupTest() {
+	s.root = NewPath("/srv", PathWithAfero(afero.NewMemMapFs()))
+	require.NoError(s.T(), FileTree(s.root, map[string]string{
+		"index.txt":       "0123456789",
+		"dir/a.txt":       "a",
+		"dir/<b>.txt":     "b",
+		"dir/secret.key":  "key",
+		".git/HEAD":       "ref",
+		"deep/er/c.txt":   "c",
+		"other/build.log": "log",
+		".gitignore":      "*.log\n",
+	}))
+	var err error
+	s.handler, err = s.root.HTTPHandler(WalkExclude("*.key"), WalkPruneDirs(".git"), WalkRespectIgnoreFiles(".gitignore"))
+	require.NoError(s.T(), err)
+}
+
+func (s *HTTPHandlerSuite) do(method string, target string, header http.Header) *http.Response {
+	r := httptest.NewRequest(method, target, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func body(t *testing.T, resp *http.Response) string {
+	contents, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(contents)
+}
+
+func (s *HTTPHandlerSuite) TestServeFile() {
+	resp := s.do(http.MethodGet, "/index.txt", nil)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("0123456789", body(s.T(), resp))
+	etag := resp.Header.Get("Etag")
+	s.NotEmpty(etag)
+
+	resp = s.do(http.MethodGet, "/index.txt", http.Header{"If-None-Match": {etag}})
+	s.Equal(http.StatusNotModified, resp.StatusCode)
+
+	resp = s.do(http.MethodGet, "/index.txt", http.Header{"Range": {"bytes=2-4"}})
+	s.Equal(http.StatusPartialContent, resp.StatusCode)
+	s.Equal("234", body(s.T(), resp))
+
+	resp = s.do(http.MethodPost, "/index.txt", nil)
+	s.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func (s *HTTPHandlerSuite) TestNotServed() {
+	for _, target := range []string{
+		"/missing.txt",
+		"/dir/secret.key",
+		"/.git/HEAD",
+		"/.git/",
+		"/other/build.log",
+		"/index.txt/foo",
+	} {
+		resp := s.do(http.MethodGet, target, nil)
+		s.Equal(http.StatusNotFound, resp.StatusCode, target)
+	}
+}
+
+func (s *HTTPHandlerSuite) TestPathTraversal() {
+	outside := s.root.Parent().Join("outside.txt")
+	require.NoError(s.T(), outside.WriteFile([]byte("outside")))
+
+	for _, target := range []string{"/../outside.txt", "/dir/../../outside.txt", "/..%2foutside.txt"} {
+		resp := s.do(http.MethodGet, target, nil)
+		s.Equal(http.StatusNotFound, resp.StatusCode, target)
+	}
+	resp := s.do(http.MethodGet, "/dir/../index.txt", nil)
+	s.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (s *HTTPHandlerSuite) TestRedirects() {
+	resp := s.do(http.MethodGet, "/dir?format=json", nil)
+	s.Equal(http.StatusMovedPermanently, resp.StatusCode)
+	s.Equal("dir/?format=json", resp.Header.Get("Location"))
+
+	resp = s.do(http.MethodGet, "/dir/a.txt/", nil)
+	s.Equal(http.StatusMovedPermanently, resp.StatusCode)
+	s.Equal("../a.txt", resp.Header.Get("Location"))
+}
+
+func (s *HTTPHandlerSuite) TestListingJSON() {
+	for _, resp := range []*http.Response{
+		s.do(http.MethodGet, "/?format=json", nil),
+		s.do(http.MethodGet, "/", http.Header{"Accept": {"application/json"}}),
+	} {
+		s.Equal(http.StatusOK, resp.StatusCode)
+		s.Equal("application/json", resp.Header.Get("Content-Type"))
+		listing := []httpDirEntry{}
+		require.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&listing))
+		names := []string{}
+		for _, entry := range listing {
+			names = append(names, entry.Name)
+		}
+		s.Equal([]string{".gitignore", "deep", "dir", "index.txt", "other"}, names)
+		s.True(listing[2].IsDir)
+		s.Equal(int64(10), listing[3].Size)
+	}
+}
+
+func (s *HTTPHandlerSuite) TestListingHTML() {
+	resp := s.do(http.MethodGet, "/dir/", nil)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	contents := body(s.T(), resp)
+	s.Contains(contents, "<title>Index of /dir/</title>")
+	s.Contains(contents, `<a href="../">../</a>`)
+	s.Contains(contents, `<a href="./a.txt">a.txt</a>`)
+	s.Contains(contents, `<a href="./%3Cb%3E.txt">&lt;b&gt;.txt</a>`)
+	s.NotContains(contents, "secret.key")
+}
+
+func TestHTTPHandler(t *testing.T) {
+	suite.Run(t, new(HTTPHandlerSuite))
+}
+
+func TestHTTPHandlerOptions(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+	require.NoError(t, root.Join("link.txt").Symlink(root.Join("file0.txt")))
+
+	_, err := root.HTTPHandler(WalkInclude("["))
+	assert.Error(t, err)
+
+	for _, tt := range []struct {
+		name     string
+		opts     []WalkOptsFunc
+		target   string
+		expected int
+	}{
+		{"symlink", nil, "/link.txt", http.StatusNotFound},
+		{"followed symlink", []WalkOptsFunc{WalkFollowSymlinks(true)}, "/link.txt", http.StatusOK},
+		{"depth", []WalkOptsFunc{WalkDepth(0)}, "/subdir/file0.txt", http.StatusNotFound},
+		{"directory within depth", []WalkOptsFunc{WalkDepth(0)}, "/subdir/", http.StatusOK},
+		{"include", []WalkOptsFunc{WalkInclude("subdir/*")}, "/file0.txt", http.StatusNotFound},
+		{"directory not included", []WalkOptsFunc{WalkInclude("subdir/*")}, "/subdir/file0.txt", http.StatusOK},
+		{"filter", []WalkOptsFunc{WalkMinimumFileSize(100)}, "/file0.txt", http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := root.HTTPHandler(tt.opts...)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
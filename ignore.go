@@ -0,0 +1,207 @@
+package pathlib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+)
+
+// ignoreRule is a single pattern parsed from an ignore file.
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+func (r *ignoreRule) matches(relative string, isDir bool) (bool, error) {
+	if r.dirOnly && !isDir {
+		return false, nil
+	}
+	return matchPattern(r.pattern, relative)
+}
+
+// parseIgnoreLine parses a single line of a .gitignore-style file. The
+// returned bool is false if the line does not contain a pattern.
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	rule := ignoreRule{}
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are ignored unless they are escaped with a backslash.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	// A trailing "/**" matches everything inside of a directory, but not the
+	// directory itself.
+	if strings.HasSuffix(line, "/**") {
+		line = strings.TrimSuffix(line, "**") + "*/**"
+	}
+	// Patterns containing a "/" are anchored to the directory of the ignore
+	// file, and patterns without one match at any depth, which is exactly how
+	// matchPattern behaves. gitignore spells negated character classes "[!...]".
+	rule.pattern = strings.ReplaceAll(line, "[!", "[^")
+	return rule, true
+}
+
+// parseIgnoreFile parses the contents of a .gitignore-style file.
+func parseIgnoreFile(contents []byte) []ignoreRule {
+	rules := []ignoreRule{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// IgnoreMatcher determines whether paths are ignored according to .gitignore-style
+// ignore files found in a directory tree. Ignore files are read lazily, and only
+// apply to the directory that contains them and its descendants. Rules in deeper
+// ignore files take precedence over rules in shallower ones, and within a single
+// file the last matching rule wins. As with git, a path can not be re-included if
+// one of its parent directories is ignored.
+type IgnoreMatcher struct {
+	root  *Path
+	names []string
+	// rules maps a directory, relative to root, to the rules that are
+	// scoped to it.
+	rules map[string][]ignoreRule
+}
+
+// NewIgnoreMatcher returns an IgnoreMatcher for the tree beneath root that reads
+// ignore files with the given names, for example ".gitignore".
+func NewIgnoreMatcher(root *Path, names ...string) *IgnoreMatcher {
+	return &IgnoreMatcher{
+		root:  root,
+		names: names,
+		rules: map[string][]ignoreRule{},
+	}
+}
+
+// AddPatterns adds .gitignore-style patterns that are scoped to the given directory,
+// relative to the root of the matcher. An empty string or "." refers to the root.
+// The patterns take precedence over those read from the directory's ignore files.
+func (m *IgnoreMatcher) AddPatterns(relativeDir string, patterns ...string) error {
+	relativeDir = normalizeRelative(relativeDir)
+	rules, err := m.rulesFor(relativeDir)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range patterns {
+		if rule, ok := parseIgnoreLine(pattern); ok {
+			rules = append(rules, rule)
+		}
+	}
+	m.rules[relativeDir] = rules
+	return nil
+}
+
+// rulesFor returns the rules scoped to the directory, reading the directory's
+// ignore files if they have not been read yet.
+func (m *IgnoreMatcher) rulesFor(relativeDir string) ([]ignoreRule, error) {
+	if rules, ok := m.rules[relativeDir]; ok {
+		return rules, nil
+	}
+	dir := m.root
+	if relativeDir != "" {
+		dir = m.root.Join(relativeDir)
+	}
+	rules := []ignoreRule{}
+	for _, name := range m.names {
+		contents, err := dir.Join(name).ReadFile()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		rules = append(rules, parseIgnoreFile(contents)...)
+	}
+	m.rules[relativeDir] = rules
+	return rules, nil
+}
+
+// matchesRules returns whether the path is ignored by the rules of any of its
+// ancestor directories, without considering whether those directories are
+// themselves ignored.
+func (m *IgnoreMatcher) matchesRules(relative string, isDir bool) (bool, error) {
+	components := strings.Split(relative, "/")
+	for depth := len(components) - 1; depth >= 0; depth-- {
+		dir := strings.Join(components[:depth], "/")
+		rules, err := m.rulesFor(dir)
+		if err != nil {
+			return false, err
+		}
+		scoped := strings.Join(components[depth:], "/")
+		for i := len(rules) - 1; i >= 0; i-- {
+			matched, err := rules[i].matches(scoped, isDir)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return !rules[i].negate, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Match returns whether the given "/"-separated path, relative to the root of the
+// matcher, is ignored. isDir specifies whether the path refers to a directory.
+func (m *IgnoreMatcher) Match(relative string, isDir bool) (bool, error) {
+	relative = normalizeRelative(relative)
+	if relative == "" {
+		return false, nil
+	}
+	components := strings.Split(relative, "/")
+	for i := 1; i < len(components); i++ {
+		ignored, err := m.matchesRules(strings.Join(components[:i], "/"), true)
+		if err != nil || ignored {
+			return ignored, err
+		}
+	}
+	return m.matchesRules(relative, isDir)
+}
+
+// IsIgnored returns whether the path is ignored. The path must be beneath the
+// root of the matcher, and is stat'ed to determine whether it is a directory. A
+// path that doesn't exist is treated as a regular file.
+func (m *IgnoreMatcher) IsIgnored(path *Path) (bool, error) {
+	relative, err := path.RelativeTo(m.root)
+	if err != nil {
+		return false, err
+	}
+	isDir := false
+	info, err := lstatIfPossible(path)
+	if err == nil {
+		isDir = IsDir(info.Mode())
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	return m.Match(relative.String(), isDir)
+}
+
+func normalizeRelative(relative string) string {
+	relative = strings.Trim(normalizePathString(relative), "/")
+	if relative == "." {
+		return ""
+	}
+	return relative
+}
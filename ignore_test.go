@@ -0,0 +1,114 @@
+package pathlib
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := NewPath("/", PathWithAfero(afero.NewMemMapFs()))
+	require.NoError(t, FileTree(root, map[string]string{
+		".gitignore": `# comment
+*.log
+!important.log
+/build/
+docs/**
+[!a]bc
+`,
+		"sub/.gitignore": `/local.txt
+!*.log
+`,
+	}))
+	matcher := NewIgnoreMatcher(root, ".gitignore")
+	require.NoError(t, matcher.AddPatterns("extra", "*.tmp"))
+
+	for _, tt := range []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"debug.log", false, true},
+		{"a/b/debug.log", false, true},
+		{"important.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"build/output", false, true},
+		{"src/build", true, false},
+		{"docs", true, false},
+		{"docs/index.md", false, true},
+		{"xbc", false, true},
+		{"abc", false, false},
+		{"sub/debug.log", false, false},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+		{"sub/deeper/local.txt", false, false},
+		{"extra/file.tmp", false, true},
+		{"file.tmp", false, false},
+		{".", true, false},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			ignored, err := matcher.Match(tt.path, tt.isDir)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ignored, ignored)
+		})
+	}
+}
+
+func TestIgnoreMatcherIsIgnored(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, root.Join(".gitignore").WriteFile([]byte("out/\n")))
+	require.NoError(t, root.Join("out").Mkdir())
+	require.NoError(t, root.Join("src").Mkdir())
+
+	matcher := NewIgnoreMatcher(root, ".gitignore")
+	for _, tt := range []struct {
+		path    *Path
+		ignored bool
+	}{
+		{root.Join("out"), true},
+		{root.Join("out", "file.txt"), true},
+		{root.Join("src"), false},
+		{root.Join("src", "out"), false},
+	} {
+		ignored, err := matcher.IsIgnored(tt.path)
+		require.NoError(t, err)
+		assert.Equal(t, tt.ignored, ignored, tt.path.String())
+	}
+}
+
+func TestWalkRespectIgnoreFiles(t *testing.T) {
+	root := NewPath("/", PathWithAfero(afero.NewMemMapFs()))
+	require.NoError(t, FileTree(root, map[string]string{
+		".gitignore":         "*.o\nvendor/\n",
+		"main.c":             "",
+		"main.o":             "",
+		"vendor/lib.c":       "",
+		"lib/.gitignore":     "!keep.o\n",
+		"lib/keep.o":         "",
+		"lib/drop.o":         "",
+		"lib/vendor/inner.c": "",
+	}))
+
+	walker, err := NewWalk(root, WalkRespectIgnoreFiles(".gitignore"), WalkVisitDirs(false))
+	require.NoError(t, err)
+	visited := []string{}
+	require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		require.NoError(t, err)
+		rel, err := path.RelativeTo(root)
+		require.NoError(t, err)
+		visited = append(visited, rel.String())
+		return nil
+	}))
+	slices.Sort(visited)
+	assert.Equal(t, []string{
+		".gitignore",
+		"lib/.gitignore",
+		"lib/keep.o",
+		"main.c",
+	}, visited)
+}
@@ -0,0 +1,257 @@
+package pathlib
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/afero"
+)
+
+// pathFS is the fs.FS returned by Path.AsFS.
+type pathFS struct {
+	root *Path
+}
+
+// AsFS returns an fs.FS rooted at the path, for use with the io/fs based APIs of
+// the standard library. The returned fs.FS also implements fs.ReadDirFS,
+// fs.StatFS, fs.ReadFileFS, fs.GlobFS and fs.SubFS. Its names are
+// "/"-separated and unrooted, as described by fs.ValidPath.
+func (p *Path) AsFS() fs.FS {
+	return &pathFS{root: p}
+}
+
+// resolve returns the Path that name refers to.
+func (f *pathFS) resolve(op string, name string) (*Path, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return f.root, nil
+	}
+	return f.root.Join(filepath.FromSlash(name)), nil
+}
+
+// fsError rewrites the path of a *fs.PathError returned by the underlying
+// filesystem to the name that was passed to the fs.FS.
+func fsError(err error, name string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{Op: pathErr.Op, Path: name, Err: pathErr.Err}
+	}
+	return err
+}
+
+// Open implements fs.FS.
+func (f *pathFS) Open(name string) (fs.File, error) {
+	p, err := f.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := p.Open()
+	if err != nil {
+		return nil, fsError(err, name)
+	}
+	return &fsFile{File: file, name: name}, nil
+}
+
+// Stat implements fs.StatFS.
+func (f *pathFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := f.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := p.Stat()
+	return info, fsError(err, name)
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (f *pathFS) ReadFile(name string) ([]byte, error) {
+	p, err := f.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := p.ReadFile()
+	return contents, fsError(err, name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *pathFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	entries, err := file.(*fsFile).ReadDir(-1)
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, err
+}
+
+// readDirFS hides every method of an fs.FS other than Open and ReadDir, so that
+// fs.Glob doesn't call back into pathFS.Glob.
+type readDirFS struct {
+	fs fs.ReadDirFS
+}
+
+func (f readDirFS) Open(name string) (fs.File, error) {
+	return f.fs.Open(name)
+}
+
+func (f readDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return f.fs.ReadDir(name)
+}
+
+// Glob implements fs.GlobFS.
+func (f *pathFS) Glob(pattern string) ([]string, error) {
+	return fs.Glob(readDirFS{fs: f}, pattern)
+}
+
+// Sub implements fs.SubFS.
+func (f *pathFS) Sub(dir string) (fs.FS, error) {
+	p, err := f.resolve("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	return p.AsFS(), nil
+}
+
+// fsFile adapts an afero.File to fs.ReadDirFile.
+type fsFile struct {
+	afero.File
+	name string
+}
+
+// ReadAt implements io.ReaderAt. Some afero filesystems, such as MemMapFs, return
+// a nil error when reading past the end of the file, which violates the contract
+// of io.ReaderAt.
+func (f *fsFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (f *fsFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	infos, err := f.File.Readdir(n)
+	entries := make([]fs.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = fsError(err, f.name)
+	}
+	return entries, err
+}
+
+// ioFS is a read-only afero.Fs backed by an fs.FS. Every method that would
+// modify the filesystem fails with syscall.EPERM.
+type ioFS struct {
+	readOnlyFs
+	fsys fs.FS
+}
+
+// NewPathFromFS returns a Path on a read-only afero.Fs that serves the contents
+// of fsys, such as an embed.FS or an fstest.MapFS. Absolute names are interpreted
+// relative to the root of fsys. Every operation that would modify the filesystem
+// fails with syscall.EPERM.
+func NewPathFromFS(fsys fs.FS, name string) *Path {
+	return NewPathAfero(name, &ioFS{fsys: fsys})
+}
+
+// fsName converts an afero name to a name that's valid for an fs.FS.
+func fsName(name string) string {
+	name = strings.TrimLeft(path.Clean(filepath.ToSlash(name)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// Name implements afero.Fs.
+func (f *ioFS) Name() string {
+	return "ioFS"
+}
+
+// Open implements afero.Fs.
+func (f *ioFS) Open(name string) (afero.File, error) {
+	file, err := f.fsys.Open(fsName(name))
+	if err != nil {
+		return nil, err
+	}
+	return &aferoFile{File: file, readOnlyFile: readOnlyFile{name: name}}, nil
+}
+
+// OpenFile implements afero.Fs. Only files opened for reading are supported.
+func (f *ioFS) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if err := checkReadOnlyFlag(name, flag); err != nil {
+		return nil, err
+	}
+	return f.Open(name)
+}
+
+// Stat implements afero.Fs.
+func (f *ioFS) Stat(name string) (os.FileInfo, error) {
+	return fs.Stat(f.fsys, fsName(name))
+}
+
+// aferoFile adapts an fs.File to afero.File.
+type aferoFile struct {
+	fs.File
+	readOnlyFile
+}
+
+// ReadAt implements afero.File, if the underlying file implements io.ReaderAt.
+func (f *aferoFile) ReadAt(p []byte, off int64) (int, error) {
+	readerAt, ok := f.File.(io.ReaderAt)
+	if !ok {
+		return 0, &os.PathError{Op: "readat", Path: f.name, Err: ErrDoesNotImplement}
+	}
+	return readerAt.ReadAt(p, off)
+}
+
+// Seek implements afero.File, if the underlying file implements io.Seeker.
+func (f *aferoFile) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: ErrDoesNotImplement}
+	}
+	return seeker.Seek(offset, whence)
+}
+
+// Readdir implements afero.File.
+func (f *aferoFile) Readdir(count int) ([]os.FileInfo, error) {
+	dir, ok := f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+	}
+	entries, err := dir.ReadDir(count)
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			return infos, infoErr
+		}
+		infos = append(infos, info)
+	}
+	return infos, err
+}
+
+// Readdirnames implements afero.File.
+func (f *aferoFile) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names, err
+}
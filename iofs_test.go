@@ -0,0 +1,79 @@
+package pathlib
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathAsFS(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		root *Path
+	}{
+		{"OsFs", NewPath(t.TempDir())},
+		{"MemMapFs", NewPath("/root", PathWithAfero(afero.NewMemMapFs()))},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, FileTree(tt.root, map[string]string{
+				"a.txt":       "a",
+				"dir/b.txt":   "bb",
+				"dir/sub/c":   "ccc",
+				"empty/.keep": "",
+			}))
+			fsys := tt.root.AsFS()
+			require.NoError(t, fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c", "empty/.keep"))
+
+			sub, err := fs.Sub(fsys, "dir")
+			require.NoError(t, err)
+			require.NoError(t, fstest.TestFS(sub, "b.txt", "sub/c"))
+
+			matches, err := fs.Glob(fsys, "dir/*.txt")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"dir/b.txt"}, matches)
+
+			_, err = fsys.Open("../a.txt")
+			assert.True(t, errors.Is(err, fs.ErrInvalid))
+			_, err = fs.Stat(fsys, "missing")
+			assert.True(t, errors.Is(err, fs.ErrNotExist))
+			var pathErr *fs.PathError
+			require.True(t, errors.As(err, &pathErr))
+			assert.Equal(t, "missing", pathErr.Path)
+		})
+	}
+}
+
+func TestNewPathFromFS(t *testing.T) {
+	mapFS := fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"dir/b.txt": {Data: []byte("bb")},
+		"dir/sub/c": {Data: []byte("ccc")},
+	}
+	root := NewPathFromFS(mapFS, "/")
+
+	contents, err := root.Join("dir", "b.txt").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bb"), contents)
+
+	walker, err := NewWalk(root, WalkAlgorithm(AlgorithmPreOrderDepthFirst), WalkSortChildren(true))
+	require.NoError(t, err)
+	relatives, err := WalkMap(walker, func(entry *WalkEntry) (string, error) {
+		return entry.Relative.String(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "dir", "dir/b.txt", "dir/sub", "dir/sub/c"}, relatives)
+
+	err = root.Join("new.txt").WriteFile([]byte("new"))
+	assert.True(t, errors.Is(err, syscall.EPERM))
+	assert.True(t, errors.Is(root.Join("a.txt").Remove(), syscall.EPERM))
+
+	// Wrapping the Path as an fs.FS again must preserve the fs.FS semantics.
+	require.NoError(t, fstest.TestFS(root.AsFS(), "a.txt", "dir/b.txt", "dir/sub/c"))
+	require.NoError(t, fstest.TestFS(NewPathFromFS(mapFS, "dir").AsFS(), "b.txt", "sub/c"))
+}
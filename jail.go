@@ -0,0 +1,320 @@
+package pathlib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// maxSymlinkHops is the maximum number of symlinks that are followed while
+// resolving a single path beneath a root, as on Linux.
+const maxSymlinkHops = 40
+
+// withinRoot returns the path of name relative to root, and whether name is
+// lexically beneath root.
+func withinRoot(root string, name string) (string, bool) {
+	relative, err := filepath.Rel(root, name)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return relative, true
+}
+
+// readlinkIfSymlink returns the target of name if it's a symlink.
+func readlinkIfSymlink(fs afero.Fs, name string) (string, bool, error) {
+	lstater, ok := fs.(afero.Lstater)
+	if !ok {
+		return "", false, nil
+	}
+	info, lstatCalled, err := lstater.LstatIfPossible(name)
+	if err != nil || !lstatCalled || !IsSymlink(info.Mode()) {
+		return "", false, err
+	}
+	linkReader, ok := fs.(afero.LinkReader)
+	if !ok {
+		return "", false, doesNotImplementErr("afero.LinkReader", fs)
+	}
+	target, err := linkReader.ReadlinkIfPossible(name)
+	return target, true, err
+}
+
+// secureResolve resolves every symlink in unsafe, which is relative to root, and
+// returns the resolved "/"-separated path relative to root. Components that do
+// not exist are resolved lexically. If followFinal is false, the final component
+// is not resolved if it's a symlink.
+//
+// If clamp is true, root is treated as the root of the filesystem: ".." in root
+// refers to root itself, and the targets of absolute symlinks are resolved
+// relative to root. Otherwise, ErrPathEscapesRoot is returned if the path would
+// leave root.
+func secureResolve(fs afero.Fs, root string, unsafe string, followFinal bool, clamp bool) (string, error) {
+	components := strings.Split(filepath.ToSlash(unsafe), "/")
+	resolved := []string{}
+	hops := 0
+	for len(components) > 0 {
+		component := components[0]
+		components = components[1:]
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				if clamp {
+					continue
+				}
+				return "", ErrPathEscapesRoot
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+		resolved = append(resolved, component)
+		if !followFinal && strings.Join(components, "") == "" {
+			break
+		}
+
+		current := filepath.Join(root, filepath.FromSlash(strings.Join(resolved, "/")))
+		target, isSymlink, err := readlinkIfSymlink(fs, current)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", err
+		}
+		if !isSymlink {
+			continue
+		}
+		hops++
+		if hops > maxSymlinkHops {
+			return "", &os.PathError{Op: "resolve", Path: current, Err: syscall.ELOOP}
+		}
+		resolved = resolved[:len(resolved)-1]
+		if filepath.IsAbs(target) {
+			resolved = resolved[:0]
+			if !clamp {
+				relative, ok := withinRoot(root, filepath.Clean(target))
+				if !ok {
+					return "", ErrPathEscapesRoot
+				}
+				target = relative
+			}
+		}
+		components = append(strings.Split(filepath.ToSlash(target), "/"), components...)
+	}
+	return strings.Join(resolved, "/"), nil
+}
+
+// SecureJoin joins the elements to the path like Join, except that the result is
+// guaranteed to be beneath the path. The path is treated as the root of the
+// filesystem: ".." components can not climb above it, and symlinks encountered
+// along the way are resolved as if the path was the filesystem root, meaning the
+// targets of absolute symlinks are interpreted relative to it. Elements that do
+// not exist are joined lexically.
+//
+// The result is only guaranteed to stay beneath the path for as long as the tree
+// isn't modified concurrently. Use PathWithJail to check every operation instead.
+func (p *Path) SecureJoin(elems ...string) (*Path, error) {
+	// The elements must not be cleaned lexically, as a ".." has to be resolved
+	// after any symlink that precedes it.
+	resolved, err := secureResolve(p.Fs(), p.String(), strings.Join(elems, "/"), true, true)
+	if err != nil {
+		return nil, err
+	}
+	if resolved == "" {
+		return NewPathAfero(p.String(), p.Fs()), nil
+	}
+	return p.Join(strings.Split(resolved, "/")...), nil
+}
+
+// jailFs is an afero.Fs that refuses to operate on names that are not beneath its
+// root, or that resolve to objects outside of it through symlinks.
+type jailFs struct {
+	fs   afero.Fs
+	root string
+}
+
+// PathWithJail confines the Path, and every Path derived from it, to the tree
+// beneath root, which is a path on the same filesystem. Every operation on a
+// name that is not beneath root, or that would follow a symlink outside of it,
+// fails with an error wrapping ErrPathEscapesRoot instead of touching the
+// filesystem. This includes Readlink and ResolveAll on symlinks that point
+// outside of root.
+//
+// Operations that act on a symlink itself, such as Lstat and Remove, don't follow
+// it. Each operation is checked before it's performed, so concurrent
+// modification of the tree by other processes can still race with the check.
+func PathWithJail(root string) PathOpts {
+	return func(p *Path) {
+		p.jail = root
+	}
+}
+
+// relative returns the path of name relative to the root, without resolving ".."
+// lexically, as it has to be resolved after any symlink that precedes it.
+func (j *jailFs) relative(name string) (string, bool) {
+	prefix := j.root
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	if name == j.root {
+		return "", true
+	}
+	if strings.HasPrefix(name, prefix) {
+		return name[len(prefix):], true
+	}
+	// The name doesn't literally start with the root, so it can only be
+	// cleaned safely if it doesn't contain any "..".
+	for _, component := range strings.Split(filepath.ToSlash(name), "/") {
+		if component == ".." {
+			return "", false
+		}
+	}
+	return withinRoot(j.root, filepath.Clean(name))
+}
+
+// check returns an error if the operation on name would escape the root.
+func (j *jailFs) check(op string, name string, followFinal bool) error {
+	relative, ok := j.relative(name)
+	if ok {
+		_, err := secureResolve(j.fs, j.root, relative, followFinal, false)
+		if err == nil || !errors.Is(err, ErrPathEscapesRoot) {
+			return err
+		}
+	}
+	return &os.PathError{Op: op, Path: name, Err: ErrPathEscapesRoot}
+}
+
+// Name implements afero.Fs.
+func (j *jailFs) Name() string {
+	return "JailFs"
+}
+
+// Create implements afero.Fs.
+func (j *jailFs) Create(name string) (afero.File, error) {
+	if err := j.check("create", name, true); err != nil {
+		return nil, err
+	}
+	return j.fs.Create(name)
+}
+
+// Mkdir implements afero.Fs.
+func (j *jailFs) Mkdir(name string, perm os.FileMode) error {
+	if err := j.check("mkdir", name, true); err != nil {
+		return err
+	}
+	return j.fs.Mkdir(name, perm)
+}
+
+// MkdirAll implements afero.Fs.
+func (j *jailFs) MkdirAll(path string, perm os.FileMode) error {
+	if err := j.check("mkdir", path, true); err != nil {
+		return err
+	}
+	return j.fs.MkdirAll(path, perm)
+}
+
+// Open implements afero.Fs.
+func (j *jailFs) Open(name string) (afero.File, error) {
+	if err := j.check("open", name, true); err != nil {
+		return nil, err
+	}
+	return j.fs.Open(name)
+}
+
+// OpenFile implements afero.Fs.
+func (j *jailFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if err := j.check("open", name, true); err != nil {
+		return nil, err
+	}
+	return j.fs.OpenFile(name, flag, perm)
+}
+
+// Remove implements afero.Fs.
+func (j *jailFs) Remove(name string) error {
+	if err := j.check("remove", name, false); err != nil {
+		return err
+	}
+	return j.fs.Remove(name)
+}
+
+// RemoveAll implements afero.Fs.
+func (j *jailFs) RemoveAll(path string) error {
+	if err := j.check("removeall", path, false); err != nil {
+		return err
+	}
+	return j.fs.RemoveAll(path)
+}
+
+// Rename implements afero.Fs.
+func (j *jailFs) Rename(oldname string, newname string) error {
+	if err := j.check("rename", oldname, false); err != nil {
+		return err
+	}
+	if err := j.check("rename", newname, false); err != nil {
+		return err
+	}
+	return j.fs.Rename(oldname, newname)
+}
+
+// Stat implements afero.Fs.
+func (j *jailFs) Stat(name string) (os.FileInfo, error) {
+	if err := j.check("stat", name, true); err != nil {
+		return nil, err
+	}
+	return j.fs.Stat(name)
+}
+
+// Chmod implements afero.Fs.
+func (j *jailFs) Chmod(name string, mode os.FileMode) error {
+	if err := j.check("chmod", name, true); err != nil {
+		return err
+	}
+	return j.fs.Chmod(name, mode)
+}
+
+// Chtimes implements afero.Fs.
+func (j *jailFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := j.check("chtimes", name, true); err != nil {
+		return err
+	}
+	return j.fs.Chtimes(name, atime, mtime)
+}
+
+// LstatIfPossible implements afero.Lstater.
+func (j *jailFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if err := j.check("lstat", name, false); err != nil {
+		return nil, true, err
+	}
+	if lstater, ok := j.fs.(afero.Lstater); ok {
+		return lstater.LstatIfPossible(name)
+	}
+	info, err := j.fs.Stat(name)
+	return info, false, err
+}
+
+// ReadlinkIfPossible implements afero.LinkReader. Reading a symlink that resolves
+// to an object outside of the root fails.
+func (j *jailFs) ReadlinkIfPossible(name string) (string, error) {
+	if err := j.check("readlink", name, true); err != nil {
+		return "", err
+	}
+	if linkReader, ok := j.fs.(afero.LinkReader); ok {
+		return linkReader.ReadlinkIfPossible(name)
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+}
+
+// SymlinkIfPossible implements afero.Linker.
+func (j *jailFs) SymlinkIfPossible(oldname string, newname string) error {
+	if err := j.check("symlink", newname, false); err != nil {
+		return err
+	}
+	if linker, ok := j.fs.(afero.Linker); ok {
+		return linker.SymlinkIfPossible(oldname, newname)
+	}
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: afero.ErrNoSymlink}
+}
@@ -0,0 +1,129 @@
+package pathlib
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newJailTree creates the following tree, and returns the root directory and the
+// directory containing it.
+//
+//	outside.txt
+//	root/file.txt
+//	root/dir/inner.txt
+//	root/dir/up -> ..
+//	root/escape -> ../outside.txt
+//	root/absolute -> /dir
+//	root/loop -> loop
+func newJailTree(t *testing.T) (*Path, *Path) {
+	parent := NewPath(t.TempDir())
+	root := parent.Join("root")
+	require.NoError(t, parent.Join("outside.txt").WriteFile([]byte("outside")))
+	require.NoError(t, root.Join("dir").MkdirAll())
+	require.NoError(t, root.Join("file.txt").WriteFile([]byte("file")))
+	require.NoError(t, root.Join("dir", "inner.txt").WriteFile([]byte("inner")))
+	require.NoError(t, root.Join("dir", "up").SymlinkStr(".."))
+	require.NoError(t, root.Join("escape").SymlinkStr("../outside.txt"))
+	require.NoError(t, root.Join("absolute").SymlinkStr("/dir"))
+	require.NoError(t, root.Join("loop").SymlinkStr("loop"))
+	return root, parent
+}
+
+func TestSecureJoin(t *testing.T) {
+	root, _ := newJailTree(t)
+
+	for _, tt := range []struct {
+		elems    []string
+		expected *Path
+	}{
+		{[]string{"file.txt"}, root.Join("file.txt")},
+		{[]string{"dir", "inner.txt"}, root.Join("dir", "inner.txt")},
+		{[]string{"../../etc/passwd"}, root.Join("etc", "passwd")},
+		{[]string{"dir", "..", "..", "file.txt"}, root.Join("file.txt")},
+		{[]string{"dir/up/dir/up/../../file.txt"}, root.Join("file.txt")},
+		{[]string{"escape"}, root.Join("outside.txt")},
+		{[]string{"absolute", "inner.txt"}, root.Join("dir", "inner.txt")},
+		{[]string{"missing", "..", "dir"}, root.Join("dir")},
+		{[]string{".."}, root},
+		{[]string{"dir/up", "..", "file.txt"}, root.Join("file.txt")},
+	} {
+		joined, err := root.SecureJoin(tt.elems...)
+		require.NoError(t, err, tt.elems)
+		assert.Equal(t, tt.expected.String(), joined.String(), tt.elems)
+	}
+
+	_, err := root.SecureJoin("loop")
+	assert.Error(t, err)
+
+	memRoot := NewPath("/root", PathWithAfero(afero.NewMemMapFs()))
+	joined, err := memRoot.SecureJoin("a", "../../b")
+	require.NoError(t, err)
+	assert.Equal(t, "/root/b", joined.String())
+}
+
+func TestPathWithJail(t *testing.T) {
+	root, parent := newJailTree(t)
+	jailed := NewPath(root.String(), PathWithJail(root.String()))
+
+	escapes := func(err error) bool {
+		return errors.Is(err, ErrPathEscapesRoot)
+	}
+
+	contents, err := jailed.Join("dir", "inner.txt").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("inner"), contents)
+	contents, err = jailed.Join("dir", "up", "file.txt").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("file"), contents)
+	require.NoError(t, jailed.Join("new", "dir").MkdirAll())
+	require.NoError(t, jailed.Join("new", "dir", "file.txt").WriteFile([]byte("new")))
+
+	_, err = jailed.Join("..", "outside.txt").ReadFile()
+	assert.True(t, escapes(err), err)
+	_, err = jailed.Join("escape").ReadFile()
+	assert.True(t, escapes(err), err)
+	assert.True(t, escapes(jailed.Join("escape").WriteFile([]byte("overwritten"))))
+	// The ".." must be resolved after the symlink, not lexically.
+	assert.True(t, escapes(jailed.Join("dir", "up", "..", "outside.txt").WriteFile([]byte("x"))))
+	_, err = NewPath(parent.Join(".", "root", "..", "outside.txt").String(), PathWithJail(root.String())).Stat()
+	assert.True(t, escapes(err), err)
+	_, err = jailed.Join("escape").Readlink()
+	assert.True(t, escapes(err), err)
+	_, err = jailed.Join("escape").ResolveAll()
+	assert.True(t, escapes(err), err)
+	_, err = jailed.Join("absolute").Stat()
+	assert.True(t, escapes(err), err)
+	_, err = jailed.Parent().Stat()
+	assert.True(t, escapes(err), err)
+
+	// Operations on the symlinks themselves don't follow them.
+	isSymlink, err := jailed.Join("escape").IsSymlink()
+	require.NoError(t, err)
+	assert.True(t, isSymlink)
+	require.NoError(t, jailed.Join("escape").Remove())
+
+	contents, err = parent.Join("outside.txt").ReadFile()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("outside"), contents)
+	_, err = parent.Join("root", "new", "dir", "file.txt").Stat()
+	assert.NoError(t, err)
+}
+
+func TestPathWithJailOptionOrder(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	jailed := NewPath("/root", PathWithJail("/root"), PathWithAfero(fs))
+	require.NoError(t, jailed.Join("file.txt").WriteFile([]byte("file")))
+	exists, err := afero.Exists(fs, "/root/file.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	err = jailed.Join("..", "other.txt").WriteFile([]byte("other"))
+	assert.True(t, errors.Is(err, ErrPathEscapesRoot))
+	_, err = fs.Stat("/other.txt")
+	assert.True(t, os.IsNotExist(err))
+}
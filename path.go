@@ -1,10 +1,12 @@
 package pathlib
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -24,6 +26,9 @@ type Path struct {
 	// Sep is the seperator used in path calculations. By default this is set to
 	// os.PathSeparator.
 	Sep string
+
+	// jail is the root set with PathWithJail, if any.
+	jail string
 }
 
 type PathOpts func(p *Path)
@@ -52,6 +57,10 @@ func NewPath(path string, opts ...PathOpts) *Path {
 	for _, opt := range opts {
 		opt(p)
 	}
+	if p.jail != "" {
+		p.fs = &jailFs{fs: p.fs, root: filepath.Clean(p.jail)}
+		p.jail = ""
+	}
 	return p
 }
 
@@ -271,6 +280,36 @@ func (p *Path) ReadDir() ([]*Path, error) {
 	return paths, err
 }
 
+// ReadDirSorted is the same as ReadDir, except the children are ordered using cmp,
+// for instance CompareNatural. Unlike ReadDir, every child is lstat'ed so that cmp
+// has access to its os.FileInfo.
+func (p *Path) ReadDirSorted(cmp CompareFunc) ([]*Path, error) {
+	children, err := p.ReadDir()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]WalkEntry, 0, len(children))
+	for _, child := range children {
+		info, err := lstatIfPossible(child)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, WalkEntry{
+			Path:     child,
+			Relative: NewPathAfero(child.Name(), p.Fs()),
+			Info:     info,
+		})
+	}
+	slices.SortStableFunc(entries, func(a WalkEntry, b WalkEntry) int {
+		return cmp(a, b)
+	})
+	sorted := make([]*Path, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry.Path)
+	}
+	return sorted, nil
+}
+
 // ReadFile reads the given path and returns the data. If the file doesn't exist
 // or is a directory, an error is returned.
 func (p *Path) ReadFile() ([]byte, error) {
@@ -487,6 +526,17 @@ func (p *Path) Lstat() (os.FileInfo, error) {
 	return stat, err
 }
 
+// lstatIfPossible calls Lstat on the path, falling back to Stat if the underlying
+// afero filesystem does not support lstat-ing. Such filesystems have no notion of
+// symlinks, so the two are equivalent.
+func lstatIfPossible(p *Path) (os.FileInfo, error) {
+	info, err := p.Lstat()
+	if errors.Is(err, ErrLstatNotPossible) || errors.Is(err, ErrDoesNotImplement) {
+		return p.Stat()
+	}
+	return info, err
+}
+
 // SymlinkStr symlinks to the target location. This will fail if the underlying
 // afero filesystem does not implement afero.Linker.
 func (p *Path) SymlinkStr(target string) error {
@@ -540,6 +590,77 @@ func IsSymlink(mode os.FileMode) bool {
 	return mode&os.ModeSymlink != 0
 }
 
+// IsFIFO returns true if the given path is a named pipe.
+func (p *Path) IsFIFO() (bool, error) {
+	fileInfo, err := p.Stat()
+	if err != nil {
+		return false, err
+	}
+	return IsFIFO(fileInfo.Mode()), nil
+}
+
+// IsFIFO returns true if the file described by the given
+// os.FileMode is a named pipe.
+func IsFIFO(mode os.FileMode) bool {
+	return mode&os.ModeNamedPipe != 0
+}
+
+// IsSocket returns true if the given path is a Unix domain socket.
+func (p *Path) IsSocket() (bool, error) {
+	fileInfo, err := p.Stat()
+	if err != nil {
+		return false, err
+	}
+	return IsSocket(fileInfo.Mode()), nil
+}
+
+// IsSocket returns true if the file described by the given
+// os.FileMode is a Unix domain socket.
+func IsSocket(mode os.FileMode) bool {
+	return mode&os.ModeSocket != 0
+}
+
+// IsDevice returns true if the given path is a device, either a block
+// device or a character device.
+func (p *Path) IsDevice() (bool, error) {
+	fileInfo, err := p.Stat()
+	if err != nil {
+		return false, err
+	}
+	return IsDevice(fileInfo.Mode()), nil
+}
+
+// IsDevice returns true if the file described by the given
+// os.FileMode is a block device or a character device.
+func IsDevice(mode os.FileMode) bool {
+	return mode&os.ModeDevice != 0
+}
+
+// IsCharDevice returns true if the given path is a character device.
+func (p *Path) IsCharDevice() (bool, error) {
+	fileInfo, err := p.Stat()
+	if err != nil {
+		return false, err
+	}
+	return IsCharDevice(fileInfo.Mode()), nil
+}
+
+// IsCharDevice returns true if the file described by the given
+// os.FileMode is a character device.
+func IsCharDevice(mode os.FileMode) bool {
+	return mode&os.ModeCharDevice != 0
+}
+
+// FileType returns the type of the object at the given path. Symlinks
+// are not followed if the filesystem supports lstat-ing.
+func (p *Path) FileType() (FileType, error) {
+	fileInfo, err := lstatIfPossible(p)
+	if err != nil {
+		return FileTypeOther, err
+	}
+	return FileTypeOf(fileInfo.Mode()), nil
+}
+
 // DeepEquals returns whether or not the path pointed to by other
 // has the same resolved filepath as self.
 func (p *Path) DeepEquals(other *Path) (bool, error) {
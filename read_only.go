@@ -0,0 +1,99 @@
+package pathlib
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// readOnlyFs implements the methods of afero.Fs that modify the filesystem for
+// the read-only filesystems that embed it. Each of them fails with
+// syscall.EPERM.
+type readOnlyFs struct{}
+
+// checkReadOnlyFlag returns an error if flag opens name for writing.
+func checkReadOnlyFlag(name string, flag int) error {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return &os.PathError{Op: "open", Path: name, Err: syscall.EPERM}
+	}
+	return nil
+}
+
+// Create implements afero.Fs.
+func (readOnlyFs) Create(name string) (afero.File, error) {
+	return nil, &os.PathError{Op: "create", Path: name, Err: syscall.EPERM}
+}
+
+// Mkdir implements afero.Fs.
+func (readOnlyFs) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: syscall.EPERM}
+}
+
+// MkdirAll implements afero.Fs.
+func (readOnlyFs) MkdirAll(path string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: path, Err: syscall.EPERM}
+}
+
+// Remove implements afero.Fs.
+func (readOnlyFs) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: syscall.EPERM}
+}
+
+// RemoveAll implements afero.Fs.
+func (readOnlyFs) RemoveAll(path string) error {
+	return &os.PathError{Op: "removeall", Path: path, Err: syscall.EPERM}
+}
+
+// Rename implements afero.Fs.
+func (readOnlyFs) Rename(oldname string, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EPERM}
+}
+
+// Chmod implements afero.Fs.
+func (readOnlyFs) Chmod(name string, mode os.FileMode) error {
+	return &os.PathError{Op: "chmod", Path: name, Err: syscall.EPERM}
+}
+
+// Chtimes implements afero.Fs.
+func (readOnlyFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: syscall.EPERM}
+}
+
+// readOnlyFile implements the methods of afero.File that modify the file for
+// the files of read-only filesystems that embed it. Each of them fails with
+// syscall.EPERM.
+type readOnlyFile struct {
+	name string
+}
+
+// Name implements afero.File.
+func (f *readOnlyFile) Name() string {
+	return f.name
+}
+
+// Sync implements afero.File.
+func (f *readOnlyFile) Sync() error {
+	return nil
+}
+
+// Write implements afero.File.
+func (f *readOnlyFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: syscall.EPERM}
+}
+
+// WriteAt implements afero.File.
+func (f *readOnlyFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: syscall.EPERM}
+}
+
+// WriteString implements afero.File.
+func (f *readOnlyFile) WriteString(s string) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: syscall.EPERM}
+}
+
+// Truncate implements afero.File.
+func (f *readOnlyFile) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.name, Err: syscall.EPERM}
+}
@@ -1,6 +1,9 @@
 package pathlib
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // The following functions provide different "scenarios"
 // that you might encounter in a filesystem tree.
@@ -36,3 +39,18 @@ func TwoFilesAtRootTwoInSubdir(root *Path) error {
 	}
 	return NFiles(subdir, 2)
 }
+
+// FileTree creates a file for each of the "/"-separated paths relative to root
+// in files, with the given contents, along with its parent directories.
+func FileTree(root *Path, files map[string]string) error {
+	for name, contents := range files {
+		file := root.Join(strings.Split(name, "/")...)
+		if err := file.Parent().MkdirAll(); err != nil {
+			return err
+		}
+		if err := file.WriteFile([]byte(contents)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package pathlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SnapshotEntry describes an object in a Snapshot.
+type SnapshotEntry struct {
+	// Path is the "/"-separated path of the object, relative to the root of
+	// the snapshot.
+	Path string `json:"path"`
+	// Type is the type of the object.
+	Type FileType `json:"type"`
+	// Size is the size of the object, as reported by its os.FileInfo.
+	Size int64 `json:"size"`
+	// Mode is the mode of the object, including its type bits.
+	Mode os.FileMode `json:"mode"`
+	// ModTime is the modification time of the object.
+	ModTime time.Time `json:"modTime"`
+	// Hash is the hex-encoded SHA-256 hash of the contents of a regular file, if
+	// the snapshot was taken with SnapshotOpts.Hash.
+	Hash string `json:"hash,omitempty"`
+	// Target is the target of a symlink, if the filesystem supports reading it.
+	Target string `json:"target,omitempty"`
+}
+
+// Snapshot describes the state of the objects in a tree at some point in time,
+// as taken by Path.Snapshot. It can be encoded as JSON, to be compared with a
+// later snapshot using Diff.
+type Snapshot struct {
+	// Root is the path that the snapshot was taken of.
+	Root string `json:"root"`
+	// Time is the time at which the snapshot was taken.
+	Time time.Time `json:"time"`
+	// Entries describes every object in the tree, ordered by path.
+	Entries []SnapshotEntry `json:"entries"`
+}
+
+// SnapshotOpts is the configuration of Path.Snapshot.
+type SnapshotOpts struct {
+	// WalkOpts configure the walk of the tree, so that its filters determine
+	// which objects are part of the snapshot.
+	WalkOpts []WalkOptsFunc
+
+	// Hash specifies that the contents of regular files should be hashed, so
+	// that changes that preserve their size and modification time are detected.
+	Hash bool
+}
+
+// SnapshotOptsFunc is a function that modifies SnapshotOpts.
+type SnapshotOptsFunc func(opts *SnapshotOpts)
+
+func SnapshotWalkOpts(opts ...WalkOptsFunc) SnapshotOptsFunc {
+	return func(config *SnapshotOpts) {
+		config.WalkOpts = append(config.WalkOpts, opts...)
+	}
+}
+
+func SnapshotHash(value bool) SnapshotOptsFunc {
+	return func(config *SnapshotOpts) {
+		config.Hash = value
+	}
+}
+
+// Snapshot walks the tree beneath the path and records the state of every
+// object it visits.
+func (p *Path) Snapshot(opts ...SnapshotOptsFunc) (*Snapshot, error) {
+	config := &SnapshotOpts{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	walker, err := NewWalk(p, config.WalkOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Root:    p.String(),
+		Time:    time.Now(),
+		Entries: []SnapshotEntry{},
+	}
+	err = walker.WalkEx(func(entry *WalkEntry) error {
+		if entry.Err != nil {
+			return entry.Err
+		}
+		if entry.Depth < 0 {
+			// The root itself, visited with IncludeRoot, which has no path
+			// relative to itself.
+			return nil
+		}
+		snapshotEntry := SnapshotEntry{
+			Path:    entry.relative,
+			Type:    FileTypeOf(modeType(entry.Info)),
+			Size:    entry.Info.Size(),
+			Mode:    entry.Info.Mode(),
+			ModTime: entry.Info.ModTime(),
+		}
+		switch snapshotEntry.Type {
+		case FileTypeRegular:
+			if config.Hash {
+				hash, err := hashFile(entry.Path)
+				if err != nil {
+					return err
+				}
+				snapshotEntry.Hash = hash
+			}
+		case FileTypeSymlink:
+			target, err := entry.Path.Readlink()
+			if err == nil {
+				snapshotEntry.Target = target.String()
+			} else if !errors.Is(err, ErrDoesNotImplement) {
+				return err
+			}
+		}
+		snapshot.Entries = append(snapshot.Entries, snapshotEntry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(snapshot.Entries, compareSnapshotEntries)
+	return snapshot, nil
+}
+
+// hashFile returns the hex-encoded SHA-256 hash of the contents of the file.
+func hashFile(p *Path) (string, error) {
+	file, err := p.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func compareSnapshotEntries(a, b SnapshotEntry) int {
+	return strings.Compare(a.Path, b.Path)
+}
+
+// SnapshotChange describes an object that is present in two snapshots, but
+// differs between them.
+type SnapshotChange struct {
+	// Before is the object in the first snapshot.
+	Before SnapshotEntry `json:"before"`
+	// After is the object in the second snapshot.
+	After SnapshotEntry `json:"after"`
+}
+
+func compareSnapshotChanges(a, b SnapshotChange) int {
+	return strings.Compare(a.After.Path, b.After.Path)
+}
+
+// SnapshotDiff describes the differences between two snapshots, as returned by
+// Diff. Each list is ordered by path.
+type SnapshotDiff struct {
+	// Added is the objects that are only present in the second snapshot.
+	Added []SnapshotEntry `json:"added"`
+	// Removed is the objects that are only present in the first snapshot.
+	Removed []SnapshotEntry `json:"removed"`
+	// Modified is the objects whose type is the same in both snapshots, but whose
+	// size, mode, modification time, hash or symlink target differ.
+	Modified []SnapshotChange `json:"modified"`
+	// TypeChanged is the objects whose type differs between the snapshots.
+	TypeChanged []SnapshotChange `json:"typeChanged"`
+}
+
+// Empty returns whether the snapshots are the same.
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 && len(d.TypeChanged) == 0
+}
+
+// Diff returns the differences between the snapshots a and b. Objects are
+// matched by their path relative to the root of each snapshot, so snapshots of
+// different trees can be compared. Hashes are only compared if both entries have
+// one, and the sizes of directories are not compared, as their meaning depends
+// on the filesystem.
+func Diff(a, b *Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{
+		Added:       []SnapshotEntry{},
+		Removed:     []SnapshotEntry{},
+		Modified:    []SnapshotChange{},
+		TypeChanged: []SnapshotChange{},
+	}
+	before := make(map[string]SnapshotEntry, len(a.Entries))
+	for _, entry := range a.Entries {
+		before[entry.Path] = entry
+	}
+	after := make(map[string]SnapshotEntry, len(b.Entries))
+	for _, entry := range b.Entries {
+		after[entry.Path] = entry
+		old, ok := before[entry.Path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, entry)
+		case old.Type != entry.Type:
+			diff.TypeChanged = append(diff.TypeChanged, SnapshotChange{Before: old, After: entry})
+		case old.modified(entry):
+			diff.Modified = append(diff.Modified, SnapshotChange{Before: old, After: entry})
+		}
+	}
+	for _, entry := range a.Entries {
+		if _, ok := after[entry.Path]; !ok {
+			diff.Removed = append(diff.Removed, entry)
+		}
+	}
+
+	slices.SortFunc(diff.Added, compareSnapshotEntries)
+	slices.SortFunc(diff.Removed, compareSnapshotEntries)
+	slices.SortFunc(diff.Modified, compareSnapshotChanges)
+	slices.SortFunc(diff.TypeChanged, compareSnapshotChanges)
+	return diff
+}
+
+// modified returns whether other, an entry of the same type, differs from e.
+func (e SnapshotEntry) modified(other SnapshotEntry) bool {
+	if e.Mode != other.Mode || !e.ModTime.Equal(other.ModTime) || e.Target != other.Target {
+		return true
+	}
+	if e.Type != FileTypeDir && e.Size != other.Size {
+		return true
+	}
+	return e.Hash != "" && other.Hash != "" && e.Hash != other.Hash
+}
@@ -0,0 +1,123 @@
+package pathlib
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSnapshotTree(t *testing.T) *Path {
+	root := NewPathAfero("/root", afero.NewMemMapFs())
+	require.NoError(t, FileTree(root, map[string]string{
+		"a.txt":       "a",
+		"dir/b.txt":   "bb",
+		"dir/c.txt":   "ccc",
+		"retyped":     "file",
+		"removed.txt": "removed",
+	}))
+	return root
+}
+
+func snapshotPaths(entries []SnapshotEntry) []string {
+	paths := []string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	return paths
+}
+
+func changedPaths(changes []SnapshotChange) []string {
+	paths := []string{}
+	for _, change := range changes {
+		paths = append(paths, change.After.Path)
+	}
+	return paths
+}
+
+func TestSnapshot(t *testing.T) {
+	root := newSnapshotTree(t)
+	snapshot, err := root.Snapshot(SnapshotHash(true), SnapshotWalkOpts(WalkExclude("removed.txt")))
+	require.NoError(t, err)
+
+	assert.Equal(t, "/root", snapshot.Root)
+	assert.Equal(t, []string{"a.txt", "dir", "dir/b.txt", "dir/c.txt", "retyped"}, snapshotPaths(snapshot.Entries))
+	a := snapshot.Entries[0]
+	assert.Equal(t, FileTypeRegular, a.Type)
+	assert.Equal(t, int64(1), a.Size)
+	assert.Equal(t, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", a.Hash)
+	assert.Equal(t, FileTypeDir, snapshot.Entries[1].Type)
+	assert.Empty(t, snapshot.Entries[1].Hash)
+}
+
+func TestSnapshotSymlink(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, root.Join("link").SymlinkStr("target"))
+	snapshot, err := root.Snapshot()
+	require.NoError(t, err)
+	require.Len(t, snapshot.Entries, 1)
+	assert.Equal(t, FileTypeSymlink, snapshot.Entries[0].Type)
+	assert.Equal(t, "target", snapshot.Entries[0].Target)
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	snapshot, err := newSnapshotTree(t).Snapshot(SnapshotHash(true))
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"type":"regular file"`)
+	decoded := &Snapshot{}
+	require.NoError(t, json.Unmarshal(encoded, decoded))
+	assert.Equal(t, snapshot.Entries[0].Path, decoded.Entries[0].Path)
+	assert.True(t, Diff(snapshot, decoded).Empty())
+}
+
+func TestDiff(t *testing.T) {
+	root := newSnapshotTree(t)
+	before, err := root.Snapshot(SnapshotHash(true))
+	require.NoError(t, err)
+
+	// A change that preserves the size and modification time is only detected
+	// by the hash.
+	bInfo, err := root.Join("dir/b.txt").Stat()
+	require.NoError(t, err)
+	require.NoError(t, root.Join("dir/b.txt").WriteFile([]byte("BB")))
+	require.NoError(t, root.Join("dir/b.txt").Chtimes(bInfo.ModTime(), bInfo.ModTime()))
+	require.NoError(t, root.Join("dir/c.txt").Chmod(0o600))
+	require.NoError(t, root.Join("removed.txt").Remove())
+	require.NoError(t, root.Join("added.txt").WriteFile([]byte("added")))
+	require.NoError(t, root.Join("retyped").Remove())
+	require.NoError(t, root.Join("retyped").Mkdir())
+
+	after, err := root.Snapshot(SnapshotHash(true))
+	require.NoError(t, err)
+	diff := Diff(before, after)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, []string{"added.txt"}, snapshotPaths(diff.Added))
+	assert.Equal(t, []string{"removed.txt"}, snapshotPaths(diff.Removed))
+	assert.Equal(t, []string{"dir/b.txt", "dir/c.txt"}, changedPaths(diff.Modified))
+	assert.Equal(t, []string{"retyped"}, changedPaths(diff.TypeChanged))
+	assert.Equal(t, FileTypeRegular, diff.TypeChanged[0].Before.Type)
+	assert.Equal(t, FileTypeDir, diff.TypeChanged[0].After.Type)
+	assert.Equal(t, os.FileMode(0o600), diff.Modified[1].After.Mode.Perm())
+
+	withoutHash, err := root.Snapshot()
+	require.NoError(t, err)
+	assert.True(t, Diff(withoutHash, after).Empty(), "hashes should only be compared if both entries have one")
+}
+
+func TestDiffDirectoryTimes(t *testing.T) {
+	entry := SnapshotEntry{Path: "dir", Type: FileTypeDir, Mode: os.ModeDir | 0o755, ModTime: time.Unix(1, 0), Size: 4096}
+	resized := entry
+	resized.Size = 8192
+	assert.True(t, Diff(&Snapshot{Entries: []SnapshotEntry{entry}}, &Snapshot{Entries: []SnapshotEntry{resized}}).Empty())
+
+	touched := entry
+	touched.ModTime = time.Unix(2, 0)
+	assert.Len(t, Diff(&Snapshot{Entries: []SnapshotEntry{entry}}, &Snapshot{Entries: []SnapshotEntry{touched}}).Modified, 1)
+}
@@ -0,0 +1,115 @@
+package pathlib
+
+import (
+	"strings"
+)
+
+// CompareFunc compares two objects for the purpose of ordering them. It returns
+// a negative number if a should come before b, a positive number if a should come
+// after b, and zero if their order doesn't matter. See WalkOpts.SortFunc and
+// Path.ReadDirSorted.
+type CompareFunc func(a WalkEntry, b WalkEntry) int
+
+// CompareName orders objects lexicographically by their name.
+func CompareName(a WalkEntry, b WalkEntry) int {
+	return strings.Compare(a.Path.Name(), b.Path.Name())
+}
+
+// CompareNameFold orders objects by their name, ignoring case.
+func CompareNameFold(a WalkEntry, b WalkEntry) int {
+	if c := strings.Compare(strings.ToLower(a.Path.Name()), strings.ToLower(b.Path.Name())); c != 0 {
+		return c
+	}
+	return CompareName(a, b)
+}
+
+// CompareNatural orders objects by their name, treating runs of digits as numbers,
+// so that "file2" comes before "file10".
+func CompareNatural(a WalkEntry, b WalkEntry) int {
+	if c := compareNatural(a.Path.Name(), b.Path.Name()); c != 0 {
+		return c
+	}
+	return CompareName(a, b)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func compareNatural(a string, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			var numA, numB string
+			numA, a = splitDigits(a)
+			numB, b = splitDigits(b)
+			numA = strings.TrimLeft(numA, "0")
+			numB = strings.TrimLeft(numB, "0")
+			// Without leading zeros, a longer number is a larger number.
+			if len(numA) != len(numB) {
+				return len(numA) - len(numB)
+			}
+			if c := strings.Compare(numA, numB); c != 0 {
+				return c
+			}
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a = a[1:]
+		b = b[1:]
+	}
+	return len(a) - len(b)
+}
+
+// splitDigits splits s into its leading run of digits and the remainder.
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// CompareMtime orders objects from the least to the most recently modified. Objects
+// with identical modification times are ordered by name.
+func CompareMtime(a WalkEntry, b WalkEntry) int {
+	if c := a.Info.ModTime().Compare(b.Info.ModTime()); c != 0 {
+		return c
+	}
+	return CompareName(a, b)
+}
+
+// CompareSize orders objects from the smallest to the largest. Objects with identical
+// sizes are ordered by name.
+func CompareSize(a WalkEntry, b WalkEntry) int {
+	if a.Info.Size() != b.Info.Size() {
+		if a.Info.Size() < b.Info.Size() {
+			return -1
+		}
+		return 1
+	}
+	return CompareName(a, b)
+}
+
+// CompareDirsFirst returns a CompareFunc that orders directories before all other
+// objects, and otherwise orders objects using cmp.
+func CompareDirsFirst(cmp CompareFunc) CompareFunc {
+	return func(a WalkEntry, b WalkEntry) int {
+		aDir, bDir := a.Info.IsDir(), b.Info.IsDir()
+		if aDir && !bDir {
+			return -1
+		}
+		if !aDir && bDir {
+			return 1
+		}
+		return cmp(a, b)
+	}
+}
+
+// CompareReverse returns a CompareFunc that reverses the order of cmp.
+func CompareReverse(cmp CompareFunc) CompareFunc {
+	return func(a WalkEntry, b WalkEntry) int {
+		return cmp(b, a)
+	}
+}
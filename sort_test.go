@@ -0,0 +1,130 @@
+package pathlib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareNatural(t *testing.T) {
+	for _, tt := range []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"file02", "file2", 0},
+		{"file2a", "file2b", -1},
+		{"a", "b", -1},
+		{"abc", "ab", 1},
+		{"10", "9", 1},
+		{"x1y2", "x1y10", -1},
+	} {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			got := compareNatural(tt.a, tt.b)
+			switch {
+			case tt.want < 0:
+				assert.Less(t, got, 0)
+			case tt.want > 0:
+				assert.Greater(t, got, 0)
+			default:
+				assert.Zero(t, got)
+			}
+		})
+	}
+}
+
+func TestReadDirSorted(t *testing.T) {
+	root := NewPath("/", PathWithAfero(afero.NewMemMapFs()))
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, child := range []struct {
+		name string
+		size int
+		dir  bool
+	}{
+		{name: "file10", size: 1},
+		{name: "File3", size: 3},
+		{name: "file2", size: 2},
+		{name: "dir1", dir: true},
+	} {
+		p := root.Join(child.name)
+		if child.dir {
+			require.NoError(t, p.Mkdir())
+		} else {
+			require.NoError(t, p.WriteFile(make([]byte, child.size)))
+		}
+		mtime := base.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, p.Chtimes(mtime, mtime))
+	}
+
+	for _, tt := range []struct {
+		name string
+		cmp  CompareFunc
+		want []string
+	}{
+		{"name", CompareName, []string{"File3", "dir1", "file10", "file2"}},
+		{"name fold", CompareNameFold, []string{"dir1", "file10", "file2", "File3"}},
+		{"natural", CompareNatural, []string{"File3", "dir1", "file2", "file10"}},
+		{"mtime", CompareMtime, []string{"file10", "File3", "file2", "dir1"}},
+		{"reverse mtime", CompareReverse(CompareMtime), []string{"dir1", "file2", "File3", "file10"}},
+		{"dirs first size", CompareDirsFirst(CompareSize), []string{"dir1", "file10", "file2", "File3"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			children, err := root.ReadDirSorted(tt.cmp)
+			require.NoError(t, err)
+			names := []string{}
+			for _, child := range children {
+				names = append(names, child.Name())
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestWalkSortFunc(t *testing.T) {
+	root := NewPath(t.TempDir())
+	for _, name := range []string{"file10", "file2", "file1"} {
+		require.NoError(t, root.Join("dir").MkdirAll())
+		require.NoError(t, root.Join(name).WriteFile([]byte{}))
+		require.NoError(t, root.Join("dir", name).WriteFile([]byte{}))
+	}
+	walker, err := NewWalk(root, WalkAlgorithm(AlgorithmPreOrderDepthFirst), WalkSortFunc(CompareDirsFirst(CompareNatural)))
+	require.NoError(t, err)
+
+	visited := []string{}
+	require.NoError(t, walker.WalkEx(func(entry *WalkEntry) error {
+		visited = append(visited, entry.Relative.String())
+		return nil
+	}))
+	assert.Equal(t, []string{
+		"dir",
+		"file1",
+		"file2",
+		"file10",
+		"dir/file1",
+		"dir/file2",
+		"dir/file10",
+	}, visited)
+}
+
+func TestCompareSize(t *testing.T) {
+	root := NewPath(t.TempDir())
+	small := root.Join("b")
+	large := root.Join("a")
+	require.NoError(t, small.WriteFile([]byte("1")))
+	require.NoError(t, large.WriteFile([]byte("12")))
+	smallInfo, err := small.Stat()
+	require.NoError(t, err)
+	largeInfo, err := large.Stat()
+	require.NoError(t, err)
+
+	smallEntry := WalkEntry{Path: small, Info: smallInfo}
+	largeEntry := WalkEntry{Path: large, Info: largeInfo}
+	assert.Less(t, CompareSize(smallEntry, largeEntry), 0)
+	assert.Greater(t, CompareSize(largeEntry, smallEntry), 0)
+	assert.Zero(t, CompareSize(smallEntry, smallEntry))
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package pathlib
+
+import "os"
+
+// fileOwner returns the user and group IDs of the owner of the file described
+// by info. ok is false if the filesystem does not provide this information.
+func fileOwner(info os.FileInfo) (uid int, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// fileID returns the device and inode numbers of the file described by info.
+// ok is false if the filesystem does not provide this information.
+func fileID(info os.FileInfo) (dev uint64, ino uint64, ok bool) {
+	return 0, 0, false
+}
+
+// fileBlocks returns the number of 512-byte blocks allocated to the file described
+// by info, and its number of hard links. ok is false if the filesystem does not
+// provide this information.
+func fileBlocks(info os.FileInfo) (blocks int64, links uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package pathlib
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the user and group IDs of the owner of the file described
+// by info. ok is false if the filesystem does not provide this information.
+func fileOwner(info os.FileInfo) (uid int, gid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// fileID returns the device and inode numbers of the file described by info.
+// ok is false if the filesystem does not provide this information.
+func fileID(info os.FileInfo) (dev uint64, ino uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(stat.Dev), uint64(stat.Ino), true //nolint:unconvert // Dev and Ino are not uint64 on every platform
+}
+
+// fileBlocks returns the number of 512-byte blocks allocated to the file described
+// by info, and its number of hard links. ok is false if the filesystem does not
+// provide this information.
+func fileBlocks(info os.FileInfo) (blocks int64, links uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int64(stat.Blocks), uint64(stat.Nlink), true //nolint:unconvert // Blocks and Nlink are not 64 bits on every platform
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"slices"
+	"sync/atomic"
+	"time"
 )
 
 // WalkOpts is the struct that defines how a walk should be performed
@@ -19,9 +21,28 @@ type WalkOpts struct {
 
 	// FollowSymlinks defines whether symlinks should be dereferenced or not. If True,
 	// the symlink itself will never be returned to WalkFunc, but rather whatever it
-	// points to.
+	// points to. A symlinked directory that refers to one of its own ancestors is
+	// not recursed into, and is reported to the WalkFunc with an error wrapping
+	// ErrWalkSymlinkLoop.
 	FollowSymlinks bool
 
+	// MaxSymlinkHops specifies how many symlinked directories may be traversed
+	// between the walk root and any object when FollowSymlinks is set. A directory
+	// that exceeds the limit is not recursed into, and is reported to the WalkFunc
+	// with an error wrapping ErrWalkSymlinkLoop. If negative, there is no limit.
+	MaxSymlinkHops int
+
+	// SameDevice causes the walk to not recurse into directories that reside on a
+	// different device than the walk root, like `find -xdev`. Such directories are
+	// still visited. If the filesystem does not expose device IDs, the walk is
+	// performed as if SameDevice was false, and Walk returns an error wrapping
+	// ErrSameDeviceNotPossible after completing.
+	SameDevice bool
+
+	// SkipMountPoints is a list of directories, such as "/proc", that should be
+	// visited but not recursed into.
+	SkipMountPoints []string
+
 	// MinimumFileSize specifies the minimum size of a file for visitation.
 	// If negative, there is no minimum size.
 	MinimumFileSize int64
@@ -40,11 +61,107 @@ type WalkOpts struct {
 	// VisitSymlinks specifies that we should visit symlinks during the walk.
 	VisitSymlinks bool
 
+	// VisitFIFOs specifies that we should visit named pipes during the walk.
+	VisitFIFOs bool
+
+	// VisitSockets specifies that we should visit Unix domain sockets during the walk.
+	VisitSockets bool
+
+	// VisitDevices specifies that we should visit block and character devices
+	// during the walk.
+	VisitDevices bool
+
+	// VisitOther specifies that we should visit objects of any other type during the
+	// walk, see FileTypeOther.
+	VisitOther bool
+
+	// IncludeRoot specifies that the root of the walk should itself be visited,
+	// subject to VisitDirs and the other filters, except for the Include and
+	// Exclude patterns. It is visited with a depth of -1 before its descendants by
+	// AlgorithmPreOrderDepthFirst, and after them by every other algorithm.
+	IncludeRoot bool
+
 	// SortChildren causes all children of a path to be lexigraphically sorted before
 	// being sent to the WalkFunc.
 	SortChildren bool
+
+	// SortFunc, if set, is used to order the children of a path before they are
+	// sent to the WalkFunc, for instance CompareNatural or CompareDirsFirst(CompareSize).
+	// It is applied after SortChildren, and the sort is stable.
+	SortFunc CompareFunc
+
+	// ResumeFrom, if set, resumes a previous walk from its Cursor, meaning only the
+	// objects that come after the cursor are visited. See WalkCursor for details.
+	ResumeFrom *WalkCursor
+
+	// Stats, if set, is reset at the start of every walk and populated with the
+	// walk's statistics as it progresses. As every walk writes to it, a Walk with
+	// Stats set must not perform several walks concurrently.
+	Stats *WalkStats
+
+	// Progress, if set, is called with the walk's statistics at most once every
+	// ProgressInterval while the walk is in progress, and once when it ends.
+	// Progress is called from the goroutine performing the walk.
+	Progress func(stats WalkStats)
+
+	// ProgressInterval is the minimum interval between calls to Progress.
+	ProgressInterval time.Duration
+
+	// LazyStat causes the walk to read the type of each object from its directory
+	// entry, using ReadDir on an *os.File, or Readdir on other afero.Files, instead
+	// of reading only names and stat'ing every object. On an OsFs, objects are then
+	// only stat'ed when something requires more than their name and type, such as
+	// the file size limits, FollowSymlinks, FilterFuncs, or the WalkFunc itself.
+	LazyStat bool
+
+	// Include is a list of patterns that an object's path, relative to the walk root,
+	// must match in order to be visited. If empty, all objects are included. Patterns
+	// use the syntax of path.Match, where "**" additionally matches any number of
+	// directories, and a pattern without a "/" is matched against the object's name.
+	// Directories that are not included are still recursed into.
+	Include []string
+
+	// Exclude is a list of patterns, using the same syntax as Include, for objects that
+	// should not be visited. Exclude takes precedence over Include. Directories that are
+	// excluded are still recursed into. Use PruneDirs to skip a directory entirely.
+	Exclude []string
+
+	// PruneDirs is a list of patterns, using the same syntax as Include, for directories
+	// that should be neither visited nor recursed into, such as ".git" or "node_modules".
+	PruneDirs []string
+
+	// IgnoreFiles is a list of names of .gitignore-style files, such as ".gitignore".
+	// If non-empty, these files are read from each directory as the walk descends, and
+	// any object they ignore is neither visited nor recursed into. See IgnoreMatcher
+	// for details on how the files are interpreted.
+	IgnoreFiles []string
+
+	// Filters is a list of FilterFuncs that an object must pass in order to be
+	// visited. Like VisitFiles and friends, filters do not affect which directories
+	// are recursed into.
+	Filters []FilterFunc
+
+	// OnEnterDir, if set, is called before the walk recurses into a directory.
+	// Returning ErrWalkSkipSubtree prevents the walk from recursing into the
+	// directory, in which case OnLeaveDir is not called for it. Returning
+	// ErrWalkSkipSiblings additionally skips the directory's remaining siblings.
+	// Returning ErrWalkStop aborts the walk, and any other error aborts the walk and
+	// is returned by Walk.
+	OnEnterDir WalkDirFunc
+
+	// OnLeaveDir, if set, is called after the walk has finished recursing into a
+	// directory that OnEnterDir was called for. It may return ErrWalkSkipSiblings to
+	// skip the directory's remaining siblings.
+	OnLeaveDir WalkDirFunc
 }
 
+// WalkDirFunc is the type of the OnEnterDir and OnLeaveDir hooks. depth is the
+// depth of the directory itself, using the same scale as WalkOpts.Depth, meaning
+// the immediate children of the walk root have a depth of 0. Hooks are called
+// regardless of whether the directory itself is visited by the WalkFunc, but are
+// never called for the walk root.
+type WalkDirFunc func(path *Path, info os.FileInfo, depth int) error
+
 // DefaultWalkOpts returns the default WalkOpts struct used when
 // walking a directory.
 func DefaultWalkOpts() *WalkOpts {
@@ -52,11 +169,16 @@ func DefaultWalkOpts() *WalkOpts {
 		Depth:           -1,
 		Algorithm:       AlgorithmBasic,
 		FollowSymlinks:  false,
+		MaxSymlinkHops:  -1,
 		MinimumFileSize: -1,
 		MaximumFileSize: -1,
 		VisitFiles:      true,
 		VisitDirs:       true,
 		VisitSymlinks:   true,
+		VisitFIFOs:      true,
+		VisitSockets:    true,
+		VisitDevices:    true,
+		VisitOther:      true,
 		SortChildren:    false,
 	}
 }
@@ -77,6 +199,22 @@ func (w *WalkOpts) MeetsMaximumSize(size int64) bool {
 	return size <= w.MaximumFileSize
 }
 
+// passesPatterns returns whether the path, relative to the walk root, passes
+// the Include and Exclude patterns.
+func (w *WalkOpts) passesPatterns(relative string) (bool, error) {
+	if len(w.Include) != 0 {
+		included, err := matchAnyPattern(w.Include, relative)
+		if err != nil || !included {
+			return false, err
+		}
+	}
+	excluded, err := matchAnyPattern(w.Exclude, relative)
+	if err != nil {
+		return false, err
+	}
+	return !excluded, nil
+}
+
 // Algorithm represents the walk algorithm that will be performed.
 type Algorithm int
 
@@ -100,10 +238,34 @@ const (
 	AlgorithmPreOrderDepthFirst
 )
 
-// Walk is an object that handles walking through a directory tree
+// Walk is an object that handles walking through a directory tree. A Walk holds
+// no state of its own while walking, so several walks may be performed with it
+// concurrently, unless WalkOpts.Stats is set.
 type Walk struct {
 	Opts *WalkOpts
 	root *Path
+
+	// cursor is the Cursor of the most recently completed walk.
+	cursor atomic.Pointer[WalkCursor]
+}
+
+// walkState is the state of a single walk performed by a Walk.
+type walkState struct {
+	*Walk
+
+	// ignore is the matcher for the IgnoreFiles of the walk.
+	ignore *IgnoreMatcher
+	// rootDevice is the device ID of the walk root, if SameDevice is set and
+	// the filesystem exposes device IDs.
+	rootDevice    uint64
+	hasRootDevice bool
+	// lastVisited is the last entry that was visited by the WalkFunc, and is used
+	// to create the Cursor.
+	lastVisited *WalkEntry
+	// stats is where the statistics of the walk are kept, if any are to be kept.
+	stats        *WalkStats
+	statsStart   time.Time
+	lastProgress time.Time
 }
 
 type WalkOptsFunc func(config *WalkOpts)
@@ -126,6 +288,24 @@ func WalkFollowSymlinks(follow bool) WalkOptsFunc {
 	}
 }
 
+func WalkMaxSymlinkHops(hops int) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.MaxSymlinkHops = hops
+	}
+}
+
+func WalkSameDevice(value bool) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.SameDevice = value
+	}
+}
+
+func WalkSkipMountPoints(paths ...string) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.SkipMountPoints = append(config.SkipMountPoints, paths...)
+	}
+}
+
 func WalkMinimumFileSize(size int64) WalkOptsFunc {
 	return func(config *WalkOpts) {
 		config.MinimumFileSize = size
@@ -156,12 +336,115 @@ func WalkVisitSymlinks(value bool) WalkOptsFunc {
 	}
 }
 
+func WalkVisitFIFOs(value bool) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.VisitFIFOs = value
+	}
+}
+
+func WalkVisitSockets(value bool) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.VisitSockets = value
+	}
+}
+
+func WalkVisitDevices(value bool) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.VisitDevices = value
+	}
+}
+
+func WalkVisitOther(value bool) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.VisitOther = value
+	}
+}
+
+func WalkIncludeRoot(value bool) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.IncludeRoot = value
+	}
+}
+
 func WalkSortChildren(value bool) WalkOptsFunc {
 	return func(config *WalkOpts) {
 		config.SortChildren = value
 	}
 }
 
+func WalkSortFunc(cmp CompareFunc) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.SortFunc = cmp
+	}
+}
+
+func WalkResumeFrom(cursor *WalkCursor) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.ResumeFrom = cursor
+	}
+}
+
+func WalkStatsInto(stats *WalkStats) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.Stats = stats
+	}
+}
+
+func WalkProgress(interval time.Duration, fn func(stats WalkStats)) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.ProgressInterval = interval
+		config.Progress = fn
+	}
+}
+
+func WalkLazyStat(value bool) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.LazyStat = value
+	}
+}
+
+func WalkInclude(patterns ...string) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.Include = append(config.Include, patterns...)
+	}
+}
+
+func WalkExclude(patterns ...string) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.Exclude = append(config.Exclude, patterns...)
+	}
+}
+
+func WalkPruneDirs(patterns ...string) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.PruneDirs = append(config.PruneDirs, patterns...)
+	}
+}
+
+func WalkRespectIgnoreFiles(names ...string) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.IgnoreFiles = append(config.IgnoreFiles, names...)
+	}
+}
+
+func WalkFilter(filters ...FilterFunc) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.Filters = append(config.Filters, filters...)
+	}
+}
+
+func WalkOnEnterDir(fn WalkDirFunc) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.OnEnterDir = fn
+	}
+}
+
+func WalkOnLeaveDir(fn WalkDirFunc) WalkOptsFunc {
+	return func(config *WalkOpts) {
+		config.OnLeaveDir = fn
+	}
+}
+
 // NewWalk returns a new Walk struct with default values applied
 func NewWalk(root *Path, opts ...WalkOptsFunc) (*Walk, error) {
 	config := DefaultWalkOpts()
@@ -192,186 +475,487 @@ func (w *Walk) maxDepthReached(currentDepth int) bool {
 	return false
 }
 
-type dfsObjectInfo struct {
-	path *Path
-	info os.FileInfo
-	err  error
+// WalkEntry describes an object encountered during a walk.
+type WalkEntry struct {
+	// Path is the path of the object.
+	Path *Path
+	// Relative is the path of the object relative to the walk root.
+	Relative *Path
+	// Depth is the depth of the object, using the same scale as WalkOpts.Depth,
+	// meaning the immediate children of the walk root have a depth of 0. The
+	// walk root itself has a depth of -1.
+	Depth int
+	// Info is the os.FileInfo of the object. It describes the symlink itself,
+	// unless WalkOpts.FollowSymlinks is set.
+	Info os.FileInfo
+	// Parent is the entry of the directory containing the object. The Parent of
+	// the walk root is nil.
+	Parent *WalkEntry
+	// Err is the error, if any, that was encountered while inspecting the object.
+	Err error
+
+	// relative is the "/"-separated form of Relative, or an empty string for
+	// the walk root.
+	relative string
+	// passesPatterns is whether the object passes the Include and Exclude
+	// patterns of the walk.
+	passesPatterns bool
+	// key uniquely identifies a directory when following symlinks. See dirKey.
+	key string
+	// symlinkHops is the number of symlinked directories between the walk root
+	// and the object, when following symlinks.
+	symlinkHops int
+	// resume is the remainder of the cursor being resumed from, relative to the
+	// object. It's only set for the walk root, and for directories that contain
+	// the cursor.
+	resume []string
+	// cursorCmp is negative if the object comes before the cursor in its parent
+	// directory, zero if the object is the one named by the cursor, and positive
+	// if it comes after. It's only meaningful if the parent's resume is set.
+	cursorCmp int
+	// prune is whether the directory should not be recursed into, for
+	// instance because doing so would cause a symlink loop.
+	prune bool
+}
+
+// WalkFuncEx is the function provided to the WalkEx function for each object.
+type WalkFuncEx func(entry *WalkEntry) error
+
+// algorithmFunc is the signature shared by the recursive implementation of
+// every Algorithm. It handles the children of dir.
+type algorithmFunc func(walkFn WalkFuncEx, dir *WalkEntry) error
+
+// recurse calls algorithm on the children of dir, wrapping the call with the
+// OnEnterDir and OnLeaveDir hooks. ErrWalkSkipSubtree returned by OnEnterDir
+// prevents the recursion, and any other error, including ErrWalkSkipSiblings, is
+// returned to the algorithm handling dir's parent.
+func (w *Walk) recurse(algorithm algorithmFunc, walkFn WalkFuncEx, dir *WalkEntry) error {
+	if w.maxDepthReached(dir.Depth+1) || dir.prune {
+		return nil
+	}
+	if w.Opts.OnEnterDir != nil {
+		if err := w.Opts.OnEnterDir(dir.Path, dir.Info, dir.Depth); err != nil {
+			if errors.Is(err, ErrWalkSkipSubtree) {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := algorithm(walkFn, dir); err != nil {
+		return err
+	}
+	if w.Opts.OnLeaveDir != nil {
+		if err := w.Opts.OnLeaveDir(dir.Path, dir.Info, dir.Depth); err != nil && !errors.Is(err, ErrWalkSkipSubtree) {
+			return err
+		}
+	}
+	return nil
 }
 
-func (w *Walk) walkDFS(walkFn WalkFunc, root *Path, currentDepth int) error {
-	if w.maxDepthReached(currentDepth) {
+// visitChild passes child to walkFn if it should be visited, and translates
+// ErrWalkSkipSubtree into the action the algorithms take: a directory is marked
+// so that it won't be recursed into, and for any other object the error means the
+// same as ErrWalkSkipSiblings.
+func (w *walkState) visitChild(walkFn WalkFuncEx, child *WalkEntry) error {
+	shouldVisit, err := w.shouldVisit(child)
+	if err != nil || !shouldVisit {
+		return err
+	}
+	err = walkFn(child)
+	if !errors.Is(err, ErrWalkSkipSubtree) {
+		return err
+	}
+	if child.Info.IsDir() {
+		child.prune = true
 		return nil
 	}
+	return ErrWalkSkipSiblings
+}
+
+// visitRoot passes the root entry to walkFn if it should be visited.
+func (w *walkState) visitRoot(walkFn WalkFuncEx, root *WalkEntry) error {
+	shouldVisit, err := w.shouldVisit(root)
+	if err != nil || !shouldVisit {
+		return err
+	}
+	return walkFn(root)
+}
 
-	var children []*dfsObjectInfo
+// skippedSiblings returns nil if err is ErrWalkSkipSiblings, which the algorithm
+// handling a directory consumes once it has stopped processing its children.
+func skippedSiblings(err error) error {
+	if errors.Is(err, ErrWalkSkipSiblings) {
+		return nil
+	}
+	return err
+}
+
+// dirKey returns a key that uniquely identifies the directory described by entry.
+// The device and inode numbers are used if the filesystem provides them, otherwise
+// the fully resolved path is used.
+func dirKey(entry *WalkEntry) string {
+	if dev, ino, ok := fileID(entry.Info); ok {
+		return fmt.Sprintf("%d:%d", dev, ino)
+	}
+	resolved, err := entry.Path.ResolveAll()
+	if err != nil {
+		// The filesystem probably doesn't support symlinks, in which case
+		// the path itself is unique.
+		return entry.Path.Clean().String()
+	}
+	return resolved.Clean().String()
+}
+
+// checkSymlinkLoop determines whether recursing into the directory described
+// by entry would exceed MaxSymlinkHops or revisit one of its ancestors. If so,
+// the entry's error is set accordingly and it is marked to not be recursed into.
+func (w *Walk) checkSymlinkLoop(entry *WalkEntry) error {
+	entry.key = dirKey(entry)
+	entry.symlinkHops = entry.Parent.symlinkHops
+	if w.Opts.MaxSymlinkHops >= 0 {
+		isSymlink, err := entry.Path.IsSymlink()
+		if err != nil && !errors.Is(err, ErrLstatNotPossible) && !errors.Is(err, ErrDoesNotImplement) {
+			return err
+		}
+		if isSymlink {
+			entry.symlinkHops++
+		}
+		if entry.symlinkHops > w.Opts.MaxSymlinkHops {
+			entry.prune = true
+			entry.Err = fmt.Errorf("%w: %s traverses more than %d symlinks", ErrWalkSymlinkLoop, entry.Path, w.Opts.MaxSymlinkHops)
+			return nil
+		}
+	}
+	for ancestor := entry.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if ancestor.key == entry.key {
+			entry.prune = true
+			entry.Err = fmt.Errorf("%w: %s refers to its ancestor %s", ErrWalkSymlinkLoop, entry.Path, ancestor.Path)
+			return nil
+		}
+	}
+	return nil
+}
+
+// checkMountPoint determines whether the directory described by entry resides on
+// a different device than the walk root, or is one of the SkipMountPoints, in which
+// case it's marked to not be recursed into.
+func (w *walkState) checkMountPoint(entry *WalkEntry) {
+	if w.hasRootDevice {
+		if device, _, ok := fileID(entry.Info); ok && device != w.rootDevice {
+			entry.prune = true
+			return
+		}
+	}
+	for _, mountPoint := range w.Opts.SkipMountPoints {
+		if entry.Path.Clean().String() == NewPathAfero(mountPoint, entry.Path.Fs()).Clean().String() {
+			entry.prune = true
+			return
+		}
+	}
+}
+
+func (w *walkState) walkDFS(walkFn WalkFuncEx, dir *WalkEntry) error {
+	if w.maxDepthReached(dir.Depth + 1) {
+		return nil
+	}
+
+	var children []*WalkEntry
+	resuming := len(dir.resume)
+
+	err := w.iterateImmediateChildren(dir, func(child *WalkEntry) error {
+		// When resuming from the cursor, the subdirectories before it have already
+		// been recursed into. If the cursor is one of this directory's children,
+		// all of them have.
+		alreadyRecursed := resuming == 1 || (resuming > 1 && child.cursorCmp < 0)
 
-	if err := w.iterateImmediateChildren(root, func(child *Path, info os.FileInfo, encounteredErr error) error {
 		// Since we are doing depth-first, we have to first recurse through all the directories,
 		// and save all non-directory objects so we can defer handling at a later time.
-		if IsDir(info.Mode()) {
-			if err := w.walkDFS(walkFn, child, currentDepth+1); err != nil && !errors.Is(err, ErrWalkSkipSubtree) {
+		if child.Info.IsDir() && !alreadyRecursed {
+			if err := w.recurse(w.walkDFS, walkFn, child); err != nil {
 				return err
 			}
 		}
 
-		children = append(children, &dfsObjectInfo{
-			path: child,
-			info: info,
-			err:  encounteredErr,
-		})
+		children = append(children, child)
 
 		return nil
-	}); err != nil {
+	})
+	// ErrWalkSkipSiblings from a subdirectory's OnEnterDir hook stops the recursion
+	// into the rest of the subdirectories, but the children collected so far,
+	// excluding the skipping directory itself, are still visited.
+	if errors.Is(err, ErrWalkSkipSiblings) {
+		err = nil
+	}
+	if err != nil {
 		return err
 	}
 
-	// Iterate over all children after all subdirs have been recursed
+	// Iterate over all children after all subdirs have been recursed. By now the
+	// subtree of every directory has been walked, so ErrWalkSkipSubtree can only
+	// prune the children of dir that haven't been visited yet.
 	for _, child := range children {
-		passesQuery, err := w.passesQuerySpecification(child.info)
-		if err != nil {
-			return err
+		if resuming == 1 && child.cursorCmp <= 0 {
+			continue
 		}
-
-		if passesQuery {
-			if err := walkFn(child.path, child.info, child.err); err != nil {
-				return err
-			}
+		if err := w.visitChild(walkFn, child); err != nil {
+			return skippedSiblings(err)
 		}
-
 	}
 	return nil
 }
 
-// iterateImmediateChildren is a function that handles discovering root's immediate children,
+// iterateImmediateChildren is a function that handles discovering dir's immediate children,
 // and will run the algorithm function for every child. The algorithm function is essentially
 // what differentiates how each walk behaves, and determines what actions to take given a
 // certain child.
-func (w *Walk) iterateImmediateChildren(root *Path, algorithmFunction WalkFunc) error {
-	children, err := root.ReadDir()
+func (w *walkState) iterateImmediateChildren(dir *WalkEntry, algorithmFunction func(child *WalkEntry) error) error {
+	children, err := w.readDir(dir.Path)
 	if err != nil {
+		w.stats.errored()
 		return err
 	}
+	w.stats.dirRead()
 
 	if w.Opts.SortChildren {
-		slices.SortFunc[[]*Path, *Path](children, func(a *Path, b *Path) int {
-			if a.String() < b.String() {
+		slices.SortFunc(children, func(a *dirChild, b *dirChild) int {
+			if a.path.String() < b.path.String() {
 				return -1
 			}
-			if a.String() == b.String() {
+			if a.path.String() == b.path.String() {
 				return 0
 			}
 			return 1
 		})
 	}
-	var info os.FileInfo
+	entries := make([]*WalkEntry, 0, len(children))
 	for _, child := range children {
-		if child.String() == root.String() {
-			continue
-		}
-		if w.Opts.FollowSymlinks {
-			info, err = child.Stat()
-			if err != nil {
-				return err
-			}
-		} else {
-			info, err = child.Lstat()
+		entry, err := w.newChildEntry(dir, child)
+		if err != nil {
+			w.stats.errored()
+			return err
 		}
-
-		if info == nil {
-			if err != nil {
-				return err
-			}
-			return ErrInfoIsNil
+		if entry != nil {
+			entries = append(entries, entry)
 		}
+	}
 
-		if algoErr := algorithmFunction(child, info, err); algoErr != nil {
+	if w.Opts.SortFunc != nil {
+		slices.SortStableFunc(entries, func(a *WalkEntry, b *WalkEntry) int {
+			return w.Opts.SortFunc(*a, *b)
+		})
+	}
+	if len(dir.resume) != 0 {
+		markCursorPositions(dir, entries)
+	}
+	for _, entry := range entries {
+		if algoErr := algorithmFunction(entry); algoErr != nil {
 			return algoErr
 		}
+		w.reportProgress(false)
 	}
 	return nil
 }
 
+// newChildEntry stats the child of dir as required and returns its WalkEntry. A nil
+// WalkEntry is returned if the child should be neither visited nor recursed into.
+func (w *walkState) newChildEntry(dir *WalkEntry, dirChild *dirChild) (*WalkEntry, error) {
+	child := dirChild.path
+	if child.String() == dir.Path.String() {
+		return nil, nil
+	}
+	depth := dir.Depth + 1
+	relative := child.Name()
+	if dir.relative != "" {
+		relative = dir.relative + "/" + relative
+	}
+	passesPatterns, err := w.Opts.passesPatterns(relative)
+	if err != nil {
+		return nil, err
+	}
+	// If the child will not be visited, and we will not recurse any deeper,
+	// there's no reason to spend a stat call on it.
+	if !passesPatterns && w.maxDepthReached(depth+1) {
+		w.stats.filtered()
+		return nil, nil
+	}
+
+	info := dirChild.info
+	if w.Opts.FollowSymlinks && (info == nil || IsSymlink(modeType(info))) {
+		info, err = child.Stat()
+		if err != nil {
+			return nil, err
+		}
+	} else if info == nil {
+		info, err = lstatIfPossible(child)
+	}
+
+	if info == nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInfoIsNil
+	}
+
+	if info.IsDir() {
+		pruned, pruneErr := matchAnyPattern(w.Opts.PruneDirs, relative)
+		if pruneErr != nil {
+			return nil, pruneErr
+		}
+		if pruned {
+			w.stats.filtered()
+			return nil, nil
+		}
+	}
+
+	if w.ignore != nil {
+		// The walk never recurses into ignored directories, so we only
+		// have to consider the rules themselves, not whether any of the
+		// parents are ignored.
+		ignored, ignoreErr := w.ignore.matchesRules(relative, info.IsDir())
+		if ignoreErr != nil {
+			return nil, ignoreErr
+		}
+		if ignored {
+			w.stats.filtered()
+			return nil, nil
+		}
+	}
+
+	entry := &WalkEntry{
+		Path:           child,
+		Relative:       NewPathAfero(relative, child.Fs()),
+		Depth:          depth,
+		Info:           info,
+		Parent:         dir,
+		Err:            err,
+		relative:       relative,
+		passesPatterns: passesPatterns,
+	}
+	if info.IsDir() {
+		w.checkMountPoint(entry)
+		if w.Opts.FollowSymlinks {
+			if loopErr := w.checkSymlinkLoop(entry); loopErr != nil {
+				return nil, loopErr
+			}
+		}
+	}
+	return entry, nil
+}
+
 // passesQuerySpecification returns whether or not the object described by
 // the os.FileInfo passes all of the query specifications listed in
 // the walk options.
 func (w *Walk) passesQuerySpecification(info os.FileInfo) (bool, error) {
-	if IsFile(info.Mode()) {
+	switch FileTypeOf(modeType(info)) {
+	case FileTypeRegular:
 		if !w.Opts.VisitFiles {
 			return false, nil
 		}
 
-		if !w.Opts.MeetsMinimumSize(info.Size()) ||
-			!w.Opts.MeetsMaximumSize(info.Size()) {
-			return false, nil
+		// Only ask for the size if there is a limit, as it might require a stat.
+		if w.Opts.MinimumFileSize >= 0 || w.Opts.MaximumFileSize >= 0 {
+			size := info.Size()
+			if !w.Opts.MeetsMinimumSize(size) || !w.Opts.MeetsMaximumSize(size) {
+				return false, nil
+			}
 		}
-	} else if IsDir(info.Mode()) && !w.Opts.VisitDirs {
-		return false, nil
-	} else if IsSymlink(info.Mode()) && !w.Opts.VisitSymlinks {
-		return false, nil
+		return true, nil
+	case FileTypeDir:
+		return w.Opts.VisitDirs, nil
+	case FileTypeSymlink:
+		return w.Opts.VisitSymlinks, nil
+	case FileTypeFIFO:
+		return w.Opts.VisitFIFOs, nil
+	case FileTypeSocket:
+		return w.Opts.VisitSockets, nil
+	case FileTypeBlockDevice, FileTypeCharDevice:
+		return w.Opts.VisitDevices, nil
+	default:
+		return w.Opts.VisitOther, nil
 	}
+}
 
+// shouldVisit returns whether or not the entry should be passed to the WalkFunc.
+func (w *walkState) shouldVisit(entry *WalkEntry) (bool, error) {
+	shouldVisit, err := w.passesFilters(entry)
+	if err == nil && !shouldVisit {
+		w.stats.filtered()
+	}
+	return shouldVisit, err
+}
+
+// passesFilters returns whether or not the entry passes the Include and Exclude
+// patterns, the query specification, and the Filters of the walk.
+func (w *Walk) passesFilters(entry *WalkEntry) (bool, error) {
+	if !entry.passesPatterns {
+		return false, nil
+	}
+	passesQuery, err := w.passesQuerySpecification(entry.Info)
+	if err != nil || !passesQuery {
+		return false, err
+	}
+	for _, filter := range w.Opts.Filters {
+		if !filter(entry.Path, entry.Info) {
+			return false, nil
+		}
+	}
 	return true, nil
 }
 
-func (w *Walk) walkBasic(walkFn WalkFunc, root *Path, currentDepth int) error {
-	if w.maxDepthReached(currentDepth) {
+func (w *walkState) walkBasic(walkFn WalkFuncEx, dir *WalkEntry) error {
+	if w.maxDepthReached(dir.Depth + 1) {
 		return nil
 	}
 
-	err := w.iterateImmediateChildren(root, func(child *Path, info os.FileInfo, encounteredErr error) error {
-		if IsDir(info.Mode()) {
-			// In the case the error is ErrWalkSkipSubtree, we ignore it as we've already
-			// exited from the recursive call. Any other error should be bubbled up.
-			if err := w.walkBasic(walkFn, child, currentDepth+1); err != nil && !errors.Is(err, ErrWalkSkipSubtree) {
-				return err
-			}
-		}
-
-		passesQuery, err := w.passesQuerySpecification(info)
-		if err != nil {
-			return err
+	resuming := len(dir.resume)
+	err := w.iterateImmediateChildren(dir, func(child *WalkEntry) error {
+		// When resuming from the cursor, the children before it have already been
+		// handled, as has the cursor itself if it's one of this directory's children.
+		if resuming != 0 && (child.cursorCmp < 0 || (child.cursorCmp == 0 && resuming == 1)) {
+			return nil
 		}
-
-		if passesQuery {
-			if err := walkFn(child, info, encounteredErr); err != nil {
+		if child.Info.IsDir() {
+			if err := w.recurse(w.walkBasic, walkFn, child); err != nil {
 				return err
 			}
 		}
-
-		return nil
+		return w.visitChild(walkFn, child)
 	})
 
-	return err
+	return skippedSiblings(err)
 }
 
-func (w *Walk) walkPreOrderDFS(walkFn WalkFunc, root *Path, currentDepth int) error {
-	if w.maxDepthReached(currentDepth) {
+func (w *walkState) walkPreOrderDFS(walkFn WalkFuncEx, dir *WalkEntry) error {
+	if w.maxDepthReached(dir.Depth + 1) {
 		return nil
 	}
-	dirs := []*Path{}
-	err := w.iterateImmediateChildren(root, func(child *Path, info os.FileInfo, encounteredErr error) error {
-		if IsDir(info.Mode()) {
+	dirs := []*WalkEntry{}
+	resuming := len(dir.resume)
+	err := w.iterateImmediateChildren(dir, func(child *WalkEntry) error {
+		if child.Info.IsDir() {
 			dirs = append(dirs, child)
 		}
 
-		passesQuery, err := w.passesQuerySpecification(info)
-		if err != nil {
-			return err
+		// When resuming from the cursor, the children up to and including it have
+		// already been visited. If the cursor is beneath one of the subdirectories,
+		// all of them have.
+		if resuming > 1 || (resuming == 1 && child.cursorCmp <= 0) {
+			return nil
 		}
 
-		if passesQuery {
-			if err := walkFn(child, info, encounteredErr); err != nil {
-				return err
-			}
-		}
-		return nil
+		return w.visitChild(walkFn, child)
 	})
-	if err != nil {
+	// ErrWalkSkipSiblings stops the iteration, so only the subdirectories up to and
+	// including the skipping child are recursed into.
+	if err = skippedSiblings(err); err != nil {
 		return err
 	}
-	for _, dir := range dirs {
-		if err := w.walkPreOrderDFS(walkFn, dir, currentDepth+1); err != nil && !errors.Is(err, ErrWalkSkipSubtree) {
-			return err
+	for _, subdir := range dirs {
+		if resuming > 1 && subdir.cursorCmp < 0 {
+			continue
+		}
+		if err := w.recurse(w.walkPreOrderDFS, walkFn, subdir); err != nil {
+			return skippedSiblings(err)
 		}
 	}
 	return nil
@@ -384,7 +968,24 @@ type WalkFunc func(path *Path, info os.FileInfo, err error) error
 // may return any of the ErrWalk* errors to control various behavior of the walker. See the documentation
 // of each error for more details.
 func (w *Walk) Walk(walkFn WalkFunc) error {
-	funcs := map[Algorithm]func(walkFn WalkFunc, root *Path, currentDepth int) error{
+	return w.WalkEx(func(entry *WalkEntry) error {
+		return walkFn(entry.Path, entry.Info, entry.Err)
+	})
+}
+
+// WalkEx is the same as Walk, except the WalkFuncEx is given a WalkEntry that
+// additionally describes the object's depth, its path relative to the walk root,
+// and its parent.
+func (w *Walk) WalkEx(walkFn WalkFuncEx) error {
+	state := &walkState{Walk: w}
+	err := state.walk(walkFn)
+	w.cursor.Store(state.cursor())
+	return err
+}
+
+// walk performs the walk, see WalkEx.
+func (w *walkState) walk(walkFn WalkFuncEx) error {
+	funcs := map[Algorithm]algorithmFunc{
 		AlgorithmBasic:               w.walkBasic,
 		AlgorithmDepthFirst:          w.walkDFS,
 		AlgorithmPostOrderDepthFirst: w.walkDFS,
@@ -394,12 +995,81 @@ func (w *Walk) Walk(walkFn WalkFunc) error {
 	if !ok {
 		return ErrInvalidAlgorithm
 	}
-	if err := algoFunc(walkFn, w.root, 0); err != nil {
-		if errors.Is(err, errWalkControl) {
-			return nil
+	for _, patterns := range [][]string{w.Opts.Include, w.Opts.Exclude, w.Opts.PruneDirs} {
+		if err := validatePatterns(patterns); err != nil {
+			return err
 		}
+	}
+	if err := w.validateCursor(); err != nil {
 		return err
 	}
-	return nil
+	rootInfo, err := w.root.Stat()
+	if err != nil {
+		return err
+	}
+	root := &WalkEntry{
+		Path:     w.root,
+		Relative: NewPathAfero(".", w.root.Fs()),
+		Depth:    -1,
+		Info:     rootInfo,
+		// The root has no name relative to itself for the patterns to match.
+		passesPatterns: true,
+	}
+	if w.Opts.ResumeFrom != nil {
+		root.resume = w.Opts.ResumeFrom.components
+	}
+	if w.Opts.FollowSymlinks {
+		root.key = dirKey(root)
+	}
+	var warning error
+	w.rootDevice, _, w.hasRootDevice = fileID(rootInfo)
+	if !w.Opts.SameDevice {
+		w.hasRootDevice = false
+	} else if !w.hasRootDevice {
+		warning = fmt.Errorf("%w: filesystem %s does not expose device IDs", ErrSameDeviceNotPossible, getFsName(w.root.Fs()))
+	}
+	if len(w.Opts.IgnoreFiles) != 0 {
+		w.ignore = NewIgnoreMatcher(w.root, w.Opts.IgnoreFiles...)
+	}
+	w.startStats()
+	defer w.reportProgress(true)
+	visit := func(entry *WalkEntry) error {
+		w.stats.visited(entry)
+		err := walkFn(entry)
+		if err == nil || errors.Is(err, errWalkControl) {
+			w.lastVisited = entry
+		} else {
+			w.stats.errored()
+		}
+		return err
+	}
+
+	// A cursor is only created after the root has been visited when it's visited
+	// first, and when it's visited last the walk has already finished.
+	rootFirst := w.Opts.Algorithm == AlgorithmPreOrderDepthFirst
+	includeRoot := w.Opts.IncludeRoot
+	if w.Opts.ResumeFrom != nil {
+		if rootFirst {
+			includeRoot = false
+		} else if len(root.resume) == 0 {
+			return warning
+		}
+	}
+
+	err = nil
+	if includeRoot && rootFirst {
+		// The root has no siblings to skip, so its subtree is still walked.
+		err = skippedSiblings(w.visitRoot(visit, root))
+	}
+	if err == nil {
+		err = algoFunc(visit, root)
+	}
+	if err == nil && includeRoot && !rootFirst {
+		err = w.visitRoot(visit, root)
+	}
+	if err != nil && !errors.Is(err, errWalkControl) {
+		return err
+	}
+	return warning
 
 }
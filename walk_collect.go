@@ -0,0 +1,80 @@
+package pathlib
+
+// Collect walks the tree and returns the paths of every object that was visited,
+// in the order of the walk.
+func (w *Walk) Collect() ([]*Path, error) {
+	return WalkMap(w, func(entry *WalkEntry) (*Path, error) {
+		return entry.Path, nil
+	})
+}
+
+// Count walks the tree and returns the number of objects that were visited.
+func (w *Walk) Count() (int, error) {
+	return WalkReduce(w, 0, func(count int, entry *WalkEntry) (int, error) {
+		return count + 1, nil
+	})
+}
+
+// Filter walks the tree and returns the paths of the visited objects that
+// satisfy filter, in the order of the walk.
+func (w *Walk) Filter(filter FilterFunc) ([]*Path, error) {
+	paths := []*Path{}
+	err := w.WalkEx(func(entry *WalkEntry) error {
+		if filter(entry.Path, entry.Info) {
+			paths = append(paths, entry.Path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// First walks the tree until it visits an object that satisfies filter, and
+// returns its path. The walk is stopped as soon as the object is found. If no
+// visited object satisfies filter, First returns a nil path and a nil error.
+func (w *Walk) First(filter FilterFunc) (*Path, error) {
+	var first *Path
+	err := w.WalkEx(func(entry *WalkEntry) error {
+		if filter(entry.Path, entry.Info) {
+			first = entry.Path
+			return ErrWalkStop
+		}
+		return nil
+	})
+	return first, err
+}
+
+// WalkMap walks the tree and returns the result of calling fn on every object
+// that was visited, in the order of the walk. fn may return any of the ErrWalk*
+// errors to control the walk, in which case its result is discarded, and any
+// other error aborts the walk and is returned by WalkMap.
+func WalkMap[T any](w *Walk, fn func(entry *WalkEntry) (T, error)) ([]T, error) {
+	results := []T{}
+	err := w.WalkEx(func(entry *WalkEntry) error {
+		result, err := fn(entry)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// WalkReduce walks the tree and combines the visited objects into a single value.
+// fn is called with the value accumulated so far, starting with initial, and
+// returns the new value. fn may return any of the ErrWalk* errors to control the
+// walk, in which case the accumulated value is left unchanged, and any other error
+// aborts the walk and is returned by WalkReduce along with the value accumulated
+// up to that point.
+func WalkReduce[T any](w *Walk, initial T, fn func(acc T, entry *WalkEntry) (T, error)) (T, error) {
+	acc := initial
+	err := w.WalkEx(func(entry *WalkEntry) error {
+		result, err := fn(acc, entry)
+		if err != nil {
+			return err
+		}
+		acc = result
+		return nil
+	})
+	return acc, err
+}
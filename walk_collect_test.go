@@ -0,0 +1,111 @@
+package pathlib
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCollectWalk(t *testing.T) (*Path, *Walk) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+	walker, err := NewWalk(root, WalkAlgorithm(AlgorithmPreOrderDepthFirst), WalkSortChildren(true))
+	require.NoError(t, err)
+	return root, walker
+}
+
+func TestWalkCollect(t *testing.T) {
+	root, walker := newCollectWalk(t)
+	paths, err := walker.Collect()
+	require.NoError(t, err)
+	assert.Equal(t, []*Path{
+		root.Join("file0.txt"),
+		root.Join("file1.txt"),
+		root.Join("subdir"),
+		root.Join("subdir", "file0.txt"),
+		root.Join("subdir", "file1.txt"),
+	}, paths)
+}
+
+func TestWalkCount(t *testing.T) {
+	_, walker := newCollectWalk(t)
+	count, err := walker.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	walker.Opts.VisitDirs = false
+	count, err = walker.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
+
+func TestWalkFilterMethod(t *testing.T) {
+	root, walker := newCollectWalk(t)
+	paths, err := walker.Filter(FilterNameMatches(regexp.MustCompile(`^file1`)))
+	require.NoError(t, err)
+	assert.Equal(t, []*Path{root.Join("file1.txt"), root.Join("subdir", "file1.txt")}, paths)
+}
+
+func TestWalkFirst(t *testing.T) {
+	root, walker := newCollectWalk(t)
+	visited := 0
+	first, err := walker.First(func(path *Path, info os.FileInfo) bool {
+		visited++
+		return info.IsDir()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, root.Join("subdir"), first)
+	assert.Equal(t, 3, visited, "walk should stop at the first match")
+
+	first, err = walker.First(func(path *Path, info os.FileInfo) bool {
+		return false
+	})
+	require.NoError(t, err)
+	assert.Nil(t, first)
+}
+
+func TestWalkMap(t *testing.T) {
+	_, walker := newCollectWalk(t)
+	relatives, err := WalkMap(walker, func(entry *WalkEntry) (string, error) {
+		if entry.Info.IsDir() {
+			return "", ErrWalkSkipSubtree
+		}
+		return entry.Relative.String(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file0.txt", "file1.txt"}, relatives)
+
+	errTest := errors.New("test")
+	relatives, err = WalkMap(walker, func(entry *WalkEntry) (string, error) {
+		if entry.Depth > 0 {
+			return "", errTest
+		}
+		return entry.Relative.String(), nil
+	})
+	assert.True(t, errors.Is(err, errTest))
+	assert.Equal(t, []string{"file0.txt", "file1.txt", "subdir"}, relatives)
+}
+
+func TestWalkReduce(t *testing.T) {
+	_, walker := newCollectWalk(t)
+	walker.Opts.VisitDirs = false
+	size, err := WalkReduce(walker, int64(0), func(size int64, entry *WalkEntry) (int64, error) {
+		return size + entry.Info.Size(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4*len("file0 contents")), size)
+
+	names, err := WalkReduce(walker, "", func(names string, entry *WalkEntry) (string, error) {
+		if strings.HasSuffix(entry.Path.Name(), "1.txt") {
+			return "", ErrWalkStop
+		}
+		return names + entry.Path.Name(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "file0.txt", names)
+}
@@ -0,0 +1,176 @@
+package pathlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// walkCursorMagic prefixes every serialized WalkCursor.
+var walkCursorMagic = []byte("PLWC")
+
+// walkCursorVersion is the version of the serialized WalkCursor format. It must
+// be incremented whenever the format changes.
+const walkCursorVersion = 1
+
+// WalkCursor is the position of a walk, which can be used to resume the walk with
+// WalkResumeFrom. A walk's position is the last object that was visited by its
+// WalkFunc, so a resumed walk starts with the object that comes after it.
+//
+// Resuming a walk relies on the children of every directory being visited in the
+// same order, so the walk must be sorted with SortChildren, and the same Algorithm
+// must be used. Objects that were created or removed in the meantime are handled
+// gracefully, according to their position in the sorted order. Decisions made by
+// the WalkFunc, such as returning ErrWalkSkipSubtree, are not part of the cursor.
+//
+// WalkCursor implements encoding.BinaryMarshaler and encoding.BinaryUnmarshaler
+// using a stable, versioned format suitable for storing on disk.
+type WalkCursor struct {
+	algorithm Algorithm
+	// components is the path of the last visited object, relative to the
+	// walk root.
+	components []string
+}
+
+func normalizeAlgorithm(algorithm Algorithm) Algorithm {
+	if algorithm == AlgorithmDepthFirst {
+		return AlgorithmPostOrderDepthFirst
+	}
+	return algorithm
+}
+
+// String returns the path of the last visited object relative to the walk root.
+func (c *WalkCursor) String() string {
+	return strings.Join(c.components, "/")
+}
+
+// MarshalBinary encodes the cursor.
+func (c *WalkCursor) MarshalBinary() ([]byte, error) {
+	data := append([]byte{}, walkCursorMagic...)
+	data = append(data, walkCursorVersion)
+	data = binary.AppendUvarint(data, uint64(c.algorithm))
+	data = binary.AppendUvarint(data, uint64(len(c.components)))
+	for _, component := range c.components {
+		data = binary.AppendUvarint(data, uint64(len(component)))
+		data = append(data, component...)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a cursor that was encoded with MarshalBinary.
+func (c *WalkCursor) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, walkCursorMagic) {
+		return fmt.Errorf("%w: missing header", ErrInvalidWalkCursor)
+	}
+	data = data[len(walkCursorMagic):]
+	if len(data) == 0 || data[0] != walkCursorVersion {
+		return fmt.Errorf("%w: unsupported version", ErrInvalidWalkCursor)
+	}
+	data = data[1:]
+
+	readUvarint := func() (uint64, error) {
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, fmt.Errorf("%w: truncated", ErrInvalidWalkCursor)
+		}
+		data = data[n:]
+		return value, nil
+	}
+	algorithm, err := readUvarint()
+	if err != nil {
+		return err
+	}
+	count, err := readUvarint()
+	if err != nil {
+		return err
+	}
+	if count > uint64(len(data)) {
+		return fmt.Errorf("%w: truncated", ErrInvalidWalkCursor)
+	}
+	components := make([]string, 0, count)
+	for i := uint64(0); i < count; i++ {
+		length, err := readUvarint()
+		if err != nil {
+			return err
+		}
+		if length > uint64(len(data)) {
+			return fmt.Errorf("%w: truncated", ErrInvalidWalkCursor)
+		}
+		components = append(components, string(data[:length]))
+		data = data[length:]
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("%w: trailing data", ErrInvalidWalkCursor)
+	}
+	c.algorithm = Algorithm(algorithm)
+	c.components = components
+	return nil
+}
+
+// Cursor returns the position of the most recently completed walk after the last
+// object that was visited by the WalkFunc. If no object was visited, the cursor
+// the walk was resumed from, if any, is returned. Otherwise, nil is returned.
+func (w *Walk) Cursor() *WalkCursor {
+	if cursor := w.cursor.Load(); cursor != nil {
+		return cursor
+	}
+	return w.Opts.ResumeFrom
+}
+
+// cursor returns the position of the walk after the last object that was visited
+// by the WalkFunc, or the cursor the walk was resumed from if there is none.
+func (w *walkState) cursor() *WalkCursor {
+	if w.lastVisited == nil {
+		return w.Opts.ResumeFrom
+	}
+	// The cursor of the walk root, visited with IncludeRoot, has no components.
+	components := []string{}
+	if w.lastVisited.relative != "" {
+		components = strings.Split(w.lastVisited.relative, "/")
+	}
+	return &WalkCursor{
+		algorithm:  normalizeAlgorithm(w.Opts.Algorithm),
+		components: components,
+	}
+}
+
+// validateCursor returns an error if the walk can't be resumed from its ResumeFrom cursor.
+func (w *Walk) validateCursor() error {
+	cursor := w.Opts.ResumeFrom
+	if cursor == nil {
+		return nil
+	}
+	if !w.Opts.SortChildren || w.Opts.SortFunc != nil {
+		return fmt.Errorf("%w: resuming a walk requires SortChildren without a SortFunc", ErrInvalidWalkCursor)
+	}
+	if cursor.algorithm != normalizeAlgorithm(w.Opts.Algorithm) {
+		return fmt.Errorf("%w: cursor was created with a different algorithm", ErrInvalidWalkCursor)
+	}
+	return nil
+}
+
+// markCursorPositions records where each of the children of dir lies relative to
+// the cursor being resumed from.
+func markCursorPositions(dir *WalkEntry, children []*WalkEntry) {
+	name := dir.resume[0]
+	found := false
+	for _, child := range children {
+		switch {
+		case found:
+			child.cursorCmp = 1
+		case child.Path.Name() == name:
+			child.cursorCmp = 0
+			found = true
+			if len(dir.resume) > 1 {
+				child.resume = dir.resume[1:]
+			}
+		case child.Path.Name() > name:
+			// The object the cursor refers to has been removed.
+			child.cursorCmp = 1
+			found = true
+		default:
+			child.cursorCmp = -1
+		}
+	}
+}
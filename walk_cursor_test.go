@@ -0,0 +1,125 @@
+package pathlib
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkCursorMarshal(t *testing.T) {
+	cursor := &WalkCursor{
+		algorithm:  AlgorithmPreOrderDepthFirst,
+		components: []string{"a", "b c", "ünïcode"},
+	}
+	data, err := cursor.MarshalBinary()
+	require.NoError(t, err)
+
+	decoded := &WalkCursor{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, cursor, decoded)
+	assert.Equal(t, "a/b c/ünïcode", decoded.String())
+
+	for _, bad := range [][]byte{
+		nil,
+		[]byte("XXXX"),
+		append([]byte("PLWC"), 99),
+		data[:len(data)-1],
+		append(data, 0),
+	} {
+		err := (&WalkCursor{}).UnmarshalBinary(bad)
+		assert.True(t, errors.Is(err, ErrInvalidWalkCursor), "unexpected error for %q: %v", bad, err)
+	}
+}
+
+func TestWalkResumeFrom(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, FileTree(root, map[string]string{
+		"a.txt":     "",
+		"b/c.txt":   "",
+		"b/d/e.txt": "",
+		"b/d/f.txt": "",
+		"b/g.txt":   "",
+		"h/i.txt":   "",
+		"j.txt":     "",
+	}))
+
+	for _, tt := range []struct {
+		algorithm   Algorithm
+		includeRoot bool
+		expectedLen int
+	}{
+		{AlgorithmBasic, false, 10},
+		{AlgorithmPostOrderDepthFirst, false, 10},
+		{AlgorithmPreOrderDepthFirst, false, 10},
+		{AlgorithmBasic, true, 11},
+		{AlgorithmPostOrderDepthFirst, true, 11},
+		{AlgorithmPreOrderDepthFirst, true, 11},
+	} {
+		t.Run(fmt.Sprintf("algorithm %d include root %t", tt.algorithm, tt.includeRoot), func(t *testing.T) {
+			// walk visits at most limit objects, starting from cursor, and returns
+			// the objects that were visited along with the resulting cursor.
+			walk := func(cursor *WalkCursor, limit int) ([]string, *WalkCursor) {
+				walker, err := NewWalk(root, WalkAlgorithm(tt.algorithm), WalkSortChildren(true), WalkIncludeRoot(tt.includeRoot), WalkResumeFrom(cursor))
+				require.NoError(t, err)
+				visited := []string{}
+				require.NoError(t, walker.WalkEx(func(entry *WalkEntry) error {
+					visited = append(visited, entry.Relative.String())
+					if len(visited) == limit {
+						return ErrWalkStop
+					}
+					return nil
+				}))
+				return visited, walker.Cursor()
+			}
+			expected, _ := walk(nil, -1)
+			require.Len(t, expected, tt.expectedLen)
+
+			// Interrupt the walk after every possible number of objects, and
+			// make sure that resuming from a stored cursor visits the rest.
+			for i := 1; i <= len(expected); i++ {
+				first, cursor := walk(nil, i)
+				data, err := cursor.MarshalBinary()
+				require.NoError(t, err)
+				stored := &WalkCursor{}
+				require.NoError(t, stored.UnmarshalBinary(data))
+
+				rest, _ := walk(stored, -1)
+				assert.Equal(t, expected, append(first, rest...), "interrupted after %d objects", i)
+			}
+		})
+	}
+}
+
+func TestWalkResumeFromRemoved(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, FileTree(root, map[string]string{"a.txt": "", "b/c.txt": "", "b/d.txt": "", "e.txt": ""}))
+	cursor := &WalkCursor{algorithm: AlgorithmBasic, components: []string{"b", "c.txt"}}
+	require.NoError(t, root.Join("b").RemoveAll())
+
+	walker, err := NewWalk(root, WalkSortChildren(true), WalkResumeFrom(cursor))
+	require.NoError(t, err)
+	visited := []string{}
+	require.NoError(t, walker.WalkEx(func(entry *WalkEntry) error {
+		visited = append(visited, entry.Relative.String())
+		return nil
+	}))
+	assert.Equal(t, []string{"e.txt"}, visited)
+}
+
+func TestWalkResumeFromInvalid(t *testing.T) {
+	root := NewPath(t.TempDir())
+	cursor := &WalkCursor{algorithm: AlgorithmBasic, components: []string{"a"}}
+	for _, opts := range [][]WalkOptsFunc{
+		{WalkResumeFrom(cursor)},
+		{WalkResumeFrom(cursor), WalkSortChildren(true), WalkSortFunc(CompareNatural)},
+		{WalkResumeFrom(cursor), WalkSortChildren(true), WalkAlgorithm(AlgorithmPreOrderDepthFirst)},
+	} {
+		walker, err := NewWalk(root, opts...)
+		require.NoError(t, err)
+		err = walker.WalkEx(func(entry *WalkEntry) error { return nil })
+		assert.True(t, errors.Is(err, ErrInvalidWalkCursor), "unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package pathlib
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// readdirBatchSize is the number of directory entries requested at a time when
+// reading directories with WalkOpts.LazyStat.
+const readdirBatchSize = 1024
+
+// dirEntryReader is implemented by afero.Files, such as *os.File, that are able
+// to return type information from directory entries without stat'ing them.
+type dirEntryReader interface {
+	ReadDir(n int) ([]fs.DirEntry, error)
+}
+
+// lazyFileInfo is an os.FileInfo for a directory entry whose name and type are
+// known, and that is only stat'ed once more information is requested. If the
+// entry can no longer be stat'ed, for instance because it was removed, only its
+// name and type are reported.
+type lazyFileInfo struct {
+	entry   fs.DirEntry
+	info    os.FileInfo
+	statted bool
+}
+
+func (l *lazyFileInfo) stat() os.FileInfo {
+	if !l.statted {
+		l.statted = true
+		l.info, _ = l.entry.Info()
+	}
+	return l.info
+}
+
+func (l *lazyFileInfo) Name() string {
+	return l.entry.Name()
+}
+
+func (l *lazyFileInfo) Size() int64 {
+	if info := l.stat(); info != nil {
+		return info.Size()
+	}
+	return 0
+}
+
+func (l *lazyFileInfo) Mode() os.FileMode {
+	if info := l.stat(); info != nil {
+		return info.Mode()
+	}
+	return l.entry.Type()
+}
+
+func (l *lazyFileInfo) ModTime() time.Time {
+	if info := l.stat(); info != nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+func (l *lazyFileInfo) IsDir() bool {
+	return l.entry.IsDir()
+}
+
+func (l *lazyFileInfo) Sys() any {
+	if info := l.stat(); info != nil {
+		return info.Sys()
+	}
+	return nil
+}
+
+// modeType returns the type bits of the mode described by info, without stat'ing
+// lazily stat'ed objects.
+func modeType(info os.FileInfo) os.FileMode {
+	if lazy, ok := info.(*lazyFileInfo); ok {
+		return lazy.entry.Type()
+	}
+	return info.Mode().Type()
+}
+
+// dirChild is a child of a directory being walked. info is nil if the child has
+// not been stat'ed yet.
+type dirChild struct {
+	path *Path
+	info os.FileInfo
+}
+
+// readDir returns the children of dir. If LazyStat is set, the os.FileInfo of each
+// child is populated from the directory entries, which for *os.File does not
+// require stat'ing the child. Otherwise, only the names of the children are read
+// and the walk must stat them itself.
+func (w *Walk) readDir(dir *Path) ([]*dirChild, error) {
+	children := []*dirChild{}
+	if !w.Opts.LazyStat {
+		paths, err := dir.ReadDir()
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			children = append(children, &dirChild{path: path})
+		}
+		return children, nil
+	}
+
+	handle, err := dir.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+
+	if reader, ok := handle.File.(dirEntryReader); ok {
+		for {
+			entries, err := reader.ReadDir(readdirBatchSize)
+			for _, entry := range entries {
+				children = append(children, &dirChild{path: dir.Join(entry.Name()), info: &lazyFileInfo{entry: entry}})
+			}
+			if errors.Is(err, io.EOF) || (err == nil && len(entries) == 0) {
+				return children, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for {
+		infos, err := handle.Readdir(readdirBatchSize)
+		for _, info := range infos {
+			children = append(children, &dirChild{path: dir.Join(info.Name()), info: info})
+		}
+		if errors.Is(err, io.EOF) || (err == nil && len(infos) == 0) {
+			return children, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
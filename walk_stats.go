@@ -0,0 +1,90 @@
+package pathlib
+
+import (
+	"time"
+)
+
+// WalkStats contains statistics about a walk. See WalkOpts.Stats and WalkOpts.Progress.
+type WalkStats struct {
+	// DirsRead is the number of directories whose children have been read.
+	DirsRead int64
+	// Visited is the number of objects that have been passed to the WalkFunc.
+	Visited int64
+	// Filtered is the number of objects that were not passed to the WalkFunc
+	// because of the walk's filters, patterns, or ignore files.
+	Filtered int64
+	// BytesVisited is the total size of the regular files that have been passed
+	// to the WalkFunc.
+	BytesVisited int64
+	// Errors is the number of errors that have been encountered, including errors
+	// reported to the WalkFunc and errors returned by it.
+	Errors int64
+	// Elapsed is the time since the walk started.
+	Elapsed time.Duration
+}
+
+// The following methods may be called on a nil *WalkStats, in which case they do
+// nothing. This way, the walk only has to keep statistics when asked to.
+
+func (s *WalkStats) dirRead() {
+	if s != nil {
+		s.DirsRead++
+	}
+}
+
+func (s *WalkStats) filtered() {
+	if s != nil {
+		s.Filtered++
+	}
+}
+
+func (s *WalkStats) errored() {
+	if s != nil {
+		s.Errors++
+	}
+}
+
+func (s *WalkStats) visited(entry *WalkEntry) {
+	if s == nil {
+		return
+	}
+	s.Visited++
+	if IsFile(modeType(entry.Info)) {
+		s.BytesVisited += entry.Info.Size()
+	}
+	if entry.Err != nil {
+		s.Errors++
+	}
+}
+
+// startStats resets the statistics of the walk, if any are to be kept.
+func (w *walkState) startStats() {
+	w.stats = w.Opts.Stats
+	if w.stats == nil && w.Opts.Progress != nil {
+		w.stats = &WalkStats{}
+	}
+	if w.stats == nil {
+		return
+	}
+	*w.stats = WalkStats{}
+	w.statsStart = time.Now()
+	w.lastProgress = w.statsStart
+}
+
+// reportProgress updates the elapsed time of the walk, and calls the Progress
+// function if ProgressInterval has passed since it was last called, or if final
+// is true.
+func (w *walkState) reportProgress(final bool) {
+	if w.stats == nil {
+		return
+	}
+	now := time.Now()
+	w.stats.Elapsed = now.Sub(w.statsStart)
+	if w.Opts.Progress == nil {
+		return
+	}
+	if final || now.Sub(w.lastProgress) >= w.Opts.ProgressInterval {
+		w.lastProgress = now
+		w.Opts.Progress(*w.stats)
+	}
+}
@@ -0,0 +1,62 @@
+package pathlib
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkStats(t *testing.T) {
+	root := NewPath(t.TempDir())
+	// file0.txt and file1.txt each contain 14 bytes.
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+	require.NoError(t, root.Join(".git").Mkdir())
+	require.NoError(t, root.Join(".git", "HEAD").WriteFile([]byte("ref")))
+
+	stats := &WalkStats{Visited: 100}
+	progress := []WalkStats{}
+	walker, err := NewWalk(
+		root,
+		WalkVisitDirs(false),
+		WalkExclude("subdir/file1.txt"),
+		WalkPruneDirs(".git"),
+		WalkStatsInto(stats),
+		WalkProgress(0, func(stats WalkStats) {
+			progress = append(progress, stats)
+		}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		return nil
+	}))
+
+	assert.Equal(t, int64(2), stats.DirsRead)
+	assert.Equal(t, int64(3), stats.Visited)
+	// subdir is filtered by VisitDirs, subdir/file1.txt by Exclude and .git by PruneDirs.
+	assert.Equal(t, int64(3), stats.Filtered)
+	assert.Equal(t, int64(3*14), stats.BytesVisited)
+	assert.Equal(t, int64(0), stats.Errors)
+	assert.Greater(t, int64(stats.Elapsed), int64(0))
+
+	require.NotEmpty(t, progress)
+	assert.Equal(t, *stats, progress[len(progress)-1])
+	for i := 1; i < len(progress); i++ {
+		assert.GreaterOrEqual(t, progress[i].Visited, progress[i-1].Visited)
+	}
+}
+
+func TestWalkStatsErrors(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+
+	stats := &WalkStats{}
+	walker, err := NewWalk(root, WalkStatsInto(stats))
+	require.NoError(t, err)
+	assert.Error(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		return os.ErrPermission
+	}))
+	assert.Equal(t, int64(1), stats.Visited)
+	assert.Equal(t, int64(1), stats.Errors)
+}
@@ -1,10 +1,13 @@
 package pathlib
 
 import (
+	"errors"
 	"fmt"
 	os "os"
+	"path"
 	"reflect"
 	"slices"
+	"sync"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -22,6 +25,7 @@ type WalkSuiteAll struct {
 	walk      *Walk
 	root      *Path
 	algorithm Algorithm
+	lazyStat  bool
 	Fs        afero.Fs
 }
 
@@ -36,6 +40,7 @@ func (w *WalkSuiteAll) SetupTest() {
 	w.walk, err = NewWalk(w.root)
 	require.NoError(w.T(), err)
 	w.walk.Opts.Algorithm = w.algorithm
+	w.walk.Opts.LazyStat = w.lazyStat
 }
 
 func (w *WalkSuiteAll) TeardownTest() {
@@ -175,9 +180,12 @@ func TestWalkSuite(t *testing.T) {
 		AlgorithmBasic,
 		AlgorithmDepthFirst,
 	} {
-		walkSuite := new(WalkSuiteAll)
-		walkSuite.algorithm = algorithm
-		suite.Run(t, walkSuite)
+		for _, lazyStat := range []bool{false, true} {
+			walkSuite := new(WalkSuiteAll)
+			walkSuite.algorithm = algorithm
+			walkSuite.lazyStat = lazyStat
+			suite.Run(t, walkSuite)
+		}
 	}
 }
 
@@ -190,11 +198,16 @@ func TestDefaultWalkOpts(t *testing.T) {
 			Depth:           -1,
 			Algorithm:       AlgorithmBasic,
 			FollowSymlinks:  false,
+			MaxSymlinkHops:  -1,
 			MinimumFileSize: -1,
 			MaximumFileSize: -1,
 			VisitFiles:      true,
 			VisitDirs:       true,
 			VisitSymlinks:   true,
+			VisitFIFOs:      true,
+			VisitSockets:    true,
+			VisitDevices:    true,
+			VisitOther:      true,
 		}},
 	}
 	for _, tt := range tests {
@@ -260,11 +273,20 @@ func TestNewWalk(t *testing.T) {
 					WalkVisitSymlinks(true),
 					WalkVisitDirs(true),
 					WalkVisitFiles(true),
+					WalkVisitFIFOs(false),
+					WalkVisitSockets(false),
+					WalkVisitDevices(false),
+					WalkVisitOther(false),
 					WalkMaximumFileSize(1000),
 					WalkMinimumFileSize(500),
 					WalkFollowSymlinks(true),
 					WalkAlgorithm(AlgorithmDepthFirst),
 					WalkDepth(10),
+					WalkMaxSymlinkHops(5),
+					WalkInclude("*.go"),
+					WalkExclude("*_test.go"),
+					WalkPruneDirs(".git"),
+					WalkRespectIgnoreFiles(".gitignore"),
 				},
 			},
 			want: &Walk{
@@ -277,6 +299,11 @@ func TestNewWalk(t *testing.T) {
 					FollowSymlinks:  true,
 					Algorithm:       AlgorithmDepthFirst,
 					Depth:           10,
+					MaxSymlinkHops:  5,
+					Include:         []string{"*.go"},
+					Exclude:         []string{"*_test.go"},
+					PruneDirs:       []string{".git"},
+					IgnoreFiles:     []string{".gitignore"},
 				},
 			},
 		},
@@ -407,7 +434,7 @@ func TestErrWalkSkipSubtree(t *testing.T) {
 	type test struct {
 		name      string
 		algorithm Algorithm
-		tree      []*Path
+		tree      map[string]string
 		skipAt    *Path
 		expected  []*Path
 	}
@@ -473,20 +500,16 @@ func TestErrWalkSkipSubtree(t *testing.T) {
 			walker, err := NewWalk(root, WalkAlgorithm(tt.algorithm), WalkVisitDirs(false), WalkVisitFiles(true), WalkSortChildren(true))
 			require.NoError(t, err)
 
-			var tree []*Path
-			if tt.tree == nil {
-				tree = []*Path{
-					NewPath("foo1.txt"),
-					NewPath("subdir1").Join("foo.txt"),
-					NewPath("subdir1").Join("subdir2", "foo.txt"),
-					NewPath("subdir1").Join("subdir2", "subdir3", "foo.txt"),
+			tree := tt.tree
+			if tree == nil {
+				tree = map[string]string{
+					"foo1.txt":                        "",
+					"subdir1/foo.txt":                 "",
+					"subdir1/subdir2/foo.txt":         "",
+					"subdir1/subdir2/subdir3/foo.txt": "",
 				}
 			}
-			for _, path := range tree {
-				p := root.JoinPath(path)
-				require.NoError(t, p.Parent().MkdirAll())
-				require.NoError(t, p.WriteFile([]byte("")))
-			}
+			require.NoError(t, FileTree(root, tree))
 
 			visited := map[string]struct{}{}
 			require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
@@ -515,3 +538,675 @@ func TestErrWalkSkipSubtree(t *testing.T) {
 		})
 	}
 }
+
+// TestWalkSkipMatrix documents the effect of the skip control errors for every
+// algorithm. The tree is walked with sorted children, and contains:
+//
+//	d1/a.txt
+//	d1/b/x.txt
+//	d1/c.txt
+//	d2/y.txt
+//	z.txt
+func TestWalkSkipMatrix(t *testing.T) {
+	type test struct {
+		name     string
+		err      error
+		skipAt   string
+		hook     bool
+		expected map[Algorithm][]string
+	}
+
+	full := map[Algorithm][]string{
+		AlgorithmBasic:               {"d1/a.txt", "d1/b/x.txt", "d1/b", "d1/c.txt", "d1", "d2/y.txt", "d2", "z.txt"},
+		AlgorithmPreOrderDepthFirst:  {"d1", "d2", "z.txt", "d1/a.txt", "d1/b", "d1/c.txt", "d1/b/x.txt", "d2/y.txt"},
+		AlgorithmPostOrderDepthFirst: {"d1/b/x.txt", "d1/a.txt", "d1/b", "d1/c.txt", "d2/y.txt", "d1", "d2", "z.txt"},
+	}
+	skippedFileSiblings := map[Algorithm][]string{
+		AlgorithmBasic:               {"d1/a.txt", "d1", "d2/y.txt", "d2", "z.txt"},
+		AlgorithmPreOrderDepthFirst:  {"d1", "d2", "z.txt", "d1/a.txt", "d2/y.txt"},
+		AlgorithmPostOrderDepthFirst: {"d1/b/x.txt", "d1/a.txt", "d2/y.txt", "d1", "d2", "z.txt"},
+	}
+
+	for _, tt := range []test{
+		{
+			// The subtree of a directory has already been walked by the time
+			// Basic and PostOrderDFS visit it, so the error has no effect.
+			name:   "SkipSubtree on directory",
+			err:    ErrWalkSkipSubtree,
+			skipAt: "d1/b",
+			expected: map[Algorithm][]string{
+				AlgorithmBasic:               full[AlgorithmBasic],
+				AlgorithmPreOrderDepthFirst:  {"d1", "d2", "z.txt", "d1/a.txt", "d1/b", "d1/c.txt", "d2/y.txt"},
+				AlgorithmPostOrderDepthFirst: full[AlgorithmPostOrderDepthFirst],
+			},
+		},
+		{
+			// For anything other than a directory, ErrWalkSkipSubtree skips
+			// the siblings that haven't been handled yet.
+			name:     "SkipSubtree on file",
+			err:      ErrWalkSkipSubtree,
+			skipAt:   "d1/a.txt",
+			expected: skippedFileSiblings,
+		},
+		{
+			name:     "SkipSiblings on file",
+			err:      ErrWalkSkipSiblings,
+			skipAt:   "d1/a.txt",
+			expected: skippedFileSiblings,
+		},
+		{
+			// The directory's own subtree is still walked.
+			name:   "SkipSiblings on directory",
+			err:    ErrWalkSkipSiblings,
+			skipAt: "d1/b",
+			expected: map[Algorithm][]string{
+				AlgorithmBasic:               {"d1/a.txt", "d1/b/x.txt", "d1/b", "d1", "d2/y.txt", "d2", "z.txt"},
+				AlgorithmPreOrderDepthFirst:  {"d1", "d2", "z.txt", "d1/a.txt", "d1/b", "d1/b/x.txt", "d2/y.txt"},
+				AlgorithmPostOrderDepthFirst: {"d1/b/x.txt", "d1/a.txt", "d1/b", "d2/y.txt", "d1", "d2", "z.txt"},
+			},
+		},
+		{
+			name:   "OnEnterDir SkipSubtree",
+			err:    ErrWalkSkipSubtree,
+			skipAt: "d1/b",
+			hook:   true,
+			expected: map[Algorithm][]string{
+				AlgorithmBasic:               {"d1/a.txt", "d1/b", "d1/c.txt", "d1", "d2/y.txt", "d2", "z.txt"},
+				AlgorithmPreOrderDepthFirst:  {"d1", "d2", "z.txt", "d1/a.txt", "d1/b", "d1/c.txt", "d2/y.txt"},
+				AlgorithmPostOrderDepthFirst: {"d1/a.txt", "d1/b", "d1/c.txt", "d2/y.txt", "d1", "d2", "z.txt"},
+			},
+		},
+		{
+			// The directory itself is skipped too, unless it has already
+			// been visited.
+			name:   "OnEnterDir SkipSiblings",
+			err:    ErrWalkSkipSiblings,
+			skipAt: "d1/b",
+			hook:   true,
+			expected: map[Algorithm][]string{
+				AlgorithmBasic:               {"d1/a.txt", "d1", "d2/y.txt", "d2", "z.txt"},
+				AlgorithmPreOrderDepthFirst:  {"d1", "d2", "z.txt", "d1/a.txt", "d1/b", "d1/c.txt", "d2/y.txt"},
+				AlgorithmPostOrderDepthFirst: {"d1/a.txt", "d2/y.txt", "d1", "d2", "z.txt"},
+			},
+		},
+	} {
+		for _, algorithm := range []Algorithm{AlgorithmBasic, AlgorithmPreOrderDepthFirst, AlgorithmPostOrderDepthFirst} {
+			t.Run(fmt.Sprintf("%s algorithm %d", tt.name, algorithm), func(t *testing.T) {
+				root := NewPath(t.TempDir())
+				require.NoError(t, FileTree(root, map[string]string{
+					"d1/a.txt":   "",
+					"d1/b/x.txt": "",
+					"d1/c.txt":   "",
+					"d2/y.txt":   "",
+					"z.txt":      "",
+				}))
+
+				relative := func(path *Path) string {
+					rel, err := path.RelativeTo(root)
+					require.NoError(t, err)
+					return rel.String()
+				}
+				opts := []WalkOptsFunc{WalkAlgorithm(algorithm), WalkSortChildren(true)}
+				if tt.hook {
+					opts = append(opts, WalkOnEnterDir(func(path *Path, info os.FileInfo, depth int) error {
+						if relative(path) == tt.skipAt {
+							return tt.err
+						}
+						return nil
+					}))
+				}
+				walker, err := NewWalk(root, opts...)
+				require.NoError(t, err)
+
+				visited := []string{}
+				require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+					require.NoError(t, err)
+					rel := relative(path)
+					visited = append(visited, rel)
+					if !tt.hook && rel == tt.skipAt {
+						return tt.err
+					}
+					return nil
+				}))
+				assert.Equal(t, tt.expected[algorithm], visited)
+			})
+		}
+	}
+}
+
+func TestWalkIncludeRoot(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		algorithm Algorithm
+		opts      []WalkOptsFunc
+		expected  []string
+	}{
+		{
+			name:      "Basic",
+			algorithm: AlgorithmBasic,
+			expected:  []string{"file0.txt", "file1.txt", "subdir/file0.txt", "subdir/file1.txt", "subdir", ". -1"},
+		},
+		{
+			name:      "PostOrderDFS",
+			algorithm: AlgorithmPostOrderDepthFirst,
+			expected:  []string{"subdir/file0.txt", "subdir/file1.txt", "file0.txt", "file1.txt", "subdir", ". -1"},
+		},
+		{
+			name:      "PreOrderDFS",
+			algorithm: AlgorithmPreOrderDepthFirst,
+			expected:  []string{". -1", "file0.txt", "file1.txt", "subdir", "subdir/file0.txt", "subdir/file1.txt"},
+		},
+		{
+			name:      "not visiting dirs",
+			algorithm: AlgorithmPreOrderDepthFirst,
+			opts:      []WalkOptsFunc{WalkVisitDirs(false)},
+			expected:  []string{"file0.txt", "file1.txt", "subdir/file0.txt", "subdir/file1.txt"},
+		},
+		{
+			name:      "patterns don't apply",
+			algorithm: AlgorithmPreOrderDepthFirst,
+			opts:      []WalkOptsFunc{WalkInclude("*.txt")},
+			expected:  []string{". -1", "file0.txt", "file1.txt", "subdir/file0.txt", "subdir/file1.txt"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewPath(t.TempDir())
+			require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+
+			opts := append([]WalkOptsFunc{WalkAlgorithm(tt.algorithm), WalkSortChildren(true), WalkIncludeRoot(true)}, tt.opts...)
+			walker, err := NewWalk(root, opts...)
+			require.NoError(t, err)
+			visited := []string{}
+			require.NoError(t, walker.WalkEx(func(entry *WalkEntry) error {
+				if entry.Path.Equals(root) {
+					visited = append(visited, fmt.Sprintf("%s %d", entry.Relative, entry.Depth))
+				} else {
+					visited = append(visited, entry.Relative.String())
+				}
+				return nil
+			}))
+			assert.Equal(t, tt.expected, visited)
+		})
+	}
+}
+
+func TestWalkIncludeRootSkipSubtree(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+
+	walker, err := NewWalk(root, WalkAlgorithm(AlgorithmPreOrderDepthFirst), WalkIncludeRoot(true))
+	require.NoError(t, err)
+	visited := 0
+	require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		visited++
+		return ErrWalkSkipSubtree
+	}))
+	assert.Equal(t, 1, visited)
+}
+
+func TestWalkVisitSockets(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, root.Join("file.txt").WriteFile([]byte("")))
+	listenUnix(t, root.Join("sock"))
+
+	for _, tt := range []struct {
+		name     string
+		opts     []WalkOptsFunc
+		expected []string
+	}{
+		{"default", nil, []string{"file.txt", "sock"}},
+		{"no sockets", []WalkOptsFunc{WalkVisitSockets(false)}, []string{"file.txt"}},
+		{"no other types", []WalkOptsFunc{WalkVisitOther(false), WalkVisitDevices(false), WalkVisitFIFOs(false)}, []string{"file.txt", "sock"}},
+		{"only sockets", []WalkOptsFunc{WalkVisitFiles(false)}, []string{"sock"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			walker, err := NewWalk(root, append([]WalkOptsFunc{WalkSortChildren(true)}, tt.opts...)...)
+			require.NoError(t, err)
+			visited := []string{}
+			require.NoError(t, walker.WalkEx(func(entry *WalkEntry) error {
+				visited = append(visited, entry.Relative.String())
+				return nil
+			}))
+			assert.Equal(t, tt.expected, visited)
+		})
+	}
+}
+
+func TestWalkPatterns(t *testing.T) {
+	tree := map[string]string{
+		"main.go":                   "",
+		"README.md":                 "",
+		"pkg/lib.go":                "",
+		"pkg/lib_test.go":           "",
+		"pkg/sub/sub.go":            "",
+		"node_modules/dep/index.js": "",
+		".git/HEAD":                 "",
+	}
+	for _, tt := range []struct {
+		name     string
+		opts     []WalkOptsFunc
+		expected []string
+	}{
+		{
+			name: "include",
+			opts: []WalkOptsFunc{WalkInclude("*.go")},
+			expected: []string{
+				"main.go",
+				"pkg/lib.go",
+				"pkg/lib_test.go",
+				"pkg/sub/sub.go",
+			},
+		},
+		{
+			name: "include double star",
+			opts: []WalkOptsFunc{WalkInclude("pkg/**/*.go")},
+			expected: []string{
+				"pkg/lib.go",
+				"pkg/lib_test.go",
+				"pkg/sub/sub.go",
+			},
+		},
+		{
+			name: "exclude takes precedence",
+			opts: []WalkOptsFunc{WalkInclude("*.go"), WalkExclude("*_test.go", "pkg/sub/**")},
+			expected: []string{
+				"main.go",
+				"pkg/lib.go",
+			},
+		},
+		{
+			name: "exclude does not prune",
+			opts: []WalkOptsFunc{WalkExclude("node_modules", ".git")},
+			expected: []string{
+				".git/HEAD",
+				"README.md",
+				"main.go",
+				"node_modules/dep",
+				"node_modules/dep/index.js",
+				"pkg",
+				"pkg/lib.go",
+				"pkg/lib_test.go",
+				"pkg/sub",
+				"pkg/sub/sub.go",
+			},
+		},
+		{
+			name: "prune",
+			opts: []WalkOptsFunc{WalkPruneDirs("node_modules", ".git", "pkg/sub")},
+			expected: []string{
+				"README.md",
+				"main.go",
+				"pkg",
+				"pkg/lib.go",
+				"pkg/lib_test.go",
+			},
+		},
+	} {
+		for _, algorithm := range []Algorithm{AlgorithmBasic, AlgorithmPostOrderDepthFirst, AlgorithmPreOrderDepthFirst} {
+			t.Run(fmt.Sprintf("%s algorithm %d", tt.name, algorithm), func(t *testing.T) {
+				root := NewPath(t.TempDir())
+				require.NoError(t, FileTree(root, tree))
+				opts := append([]WalkOptsFunc{WalkAlgorithm(algorithm)}, tt.opts...)
+				walker, err := NewWalk(root, opts...)
+				require.NoError(t, err)
+
+				visited := []string{}
+				require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+					require.NoError(t, err)
+					rel, err := path.RelativeTo(root)
+					require.NoError(t, err)
+					visited = append(visited, rel.String())
+					return nil
+				}))
+				slices.Sort(visited)
+				assert.Equal(t, tt.expected, visited)
+			})
+		}
+	}
+}
+
+func TestWalkConcurrent(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+	require.NoError(t, root.Join(".gitignore").WriteFile([]byte("file1.txt\n")))
+
+	// A Walk holds no state of its own while walking, so it may be used by
+	// several goroutines at once.
+	walker, err := NewWalk(root, WalkSortChildren(true), WalkSameDevice(true), WalkRespectIgnoreFiles(".gitignore"))
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			visited := 0
+			assert.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+				visited++
+				return err
+			}))
+			assert.Equal(t, 4, visited)
+		}()
+	}
+	wg.Wait()
+
+	cursor := walker.Cursor()
+	require.NotNil(t, cursor)
+	assert.Equal(t, []string{"subdir"}, cursor.components)
+}
+
+func TestWalkBadPattern(t *testing.T) {
+	walker, err := NewWalk(NewPath(t.TempDir()), WalkExclude("["))
+	require.NoError(t, err)
+	err = walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		return nil
+	})
+	assert.True(t, errors.Is(err, path.ErrBadPattern), "unexpected error: %v", err)
+}
+
+func TestWalkDirHooks(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		algorithm Algorithm
+		skip      string
+		expected  []string
+	}{
+		{
+			name:      "Basic",
+			algorithm: AlgorithmBasic,
+			expected: []string{
+				"visit a.txt",
+				"enter d1 0",
+				"visit d1/b.txt",
+				"enter d1/d2 1",
+				"visit d1/d2/c.txt",
+				"leave d1/d2 1",
+				"visit d1/d2",
+				"leave d1 0",
+				"visit d1",
+			},
+		},
+		{
+			name:      "PostOrderDFS",
+			algorithm: AlgorithmPostOrderDepthFirst,
+			expected: []string{
+				"enter d1 0",
+				"enter d1/d2 1",
+				"visit d1/d2/c.txt",
+				"leave d1/d2 1",
+				"visit d1/b.txt",
+				"visit d1/d2",
+				"leave d1 0",
+				"visit a.txt",
+				"visit d1",
+			},
+		},
+		{
+			name:      "PreOrderDFS",
+			algorithm: AlgorithmPreOrderDepthFirst,
+			expected: []string{
+				"visit a.txt",
+				"visit d1",
+				"enter d1 0",
+				"visit d1/b.txt",
+				"visit d1/d2",
+				"enter d1/d2 1",
+				"visit d1/d2/c.txt",
+				"leave d1/d2 1",
+				"leave d1 0",
+			},
+		},
+		{
+			name:      "PreOrderDFS skip subtree",
+			algorithm: AlgorithmPreOrderDepthFirst,
+			skip:      "d1/d2",
+			expected: []string{
+				"visit a.txt",
+				"visit d1",
+				"enter d1 0",
+				"visit d1/b.txt",
+				"visit d1/d2",
+				"enter d1/d2 1",
+				"leave d1 0",
+			},
+		},
+		{
+			name:      "PostOrderDFS skip subtree",
+			algorithm: AlgorithmPostOrderDepthFirst,
+			skip:      "d1",
+			expected: []string{
+				"enter d1 0",
+				"visit a.txt",
+				"visit d1",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewPath(t.TempDir())
+			require.NoError(t, FileTree(root, map[string]string{
+				"a.txt":       "",
+				"d1/b.txt":    "",
+				"d1/d2/c.txt": "",
+			}))
+
+			events := []string{}
+			relative := func(path *Path) string {
+				rel, err := path.RelativeTo(root)
+				require.NoError(t, err)
+				return rel.String()
+			}
+			walker, err := NewWalk(
+				root,
+				WalkAlgorithm(tt.algorithm),
+				WalkSortChildren(true),
+				WalkOnEnterDir(func(path *Path, info os.FileInfo, depth int) error {
+					require.True(t, info.IsDir())
+					events = append(events, fmt.Sprintf("enter %s %d", relative(path), depth))
+					if relative(path) == tt.skip {
+						return ErrWalkSkipSubtree
+					}
+					return nil
+				}),
+				WalkOnLeaveDir(func(path *Path, info os.FileInfo, depth int) error {
+					events = append(events, fmt.Sprintf("leave %s %d", relative(path), depth))
+					return nil
+				}),
+			)
+			require.NoError(t, err)
+			require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+				events = append(events, "visit "+relative(path))
+				return nil
+			}))
+			assert.Equal(t, tt.expected, events)
+		})
+	}
+}
+
+func TestWalkDirHooksMaxDepth(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+
+	hook := func(path *Path, info os.FileInfo, depth int) error {
+		t.Errorf("hook called for %s, which is beyond the max depth", path)
+		return nil
+	}
+	walker, err := NewWalk(root, WalkDepth(0), WalkOnEnterDir(hook), WalkOnLeaveDir(hook))
+	require.NoError(t, err)
+	require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		return nil
+	}))
+}
+
+func (w *WalkSuiteAll) TestWalkEx() {
+	require.NoError(w.T(), TwoFilesAtRootTwoInSubdir(w.root))
+
+	walkFunc := MockWalkFuncEx{}
+	walkFunc.On("Execute", mock.Anything).Return(nil)
+	w.NoError(w.walk.WalkEx(walkFunc.Execute))
+	walkFunc.AssertNumberOfCalls(w.T(), "Execute", 5)
+
+	for _, call := range walkFunc.Calls {
+		entry := call.Arguments.Get(0).(*WalkEntry)
+		w.NoError(entry.Err)
+
+		relative, err := entry.Path.RelativeTo(w.root)
+		require.NoError(w.T(), err)
+		w.Equal(relative.String(), entry.Relative.String())
+		w.Equal(len(relative.Parts())-1, entry.Depth)
+
+		require.NotNil(w.T(), entry.Parent)
+		w.True(entry.Parent.Path.Equals(entry.Path.Parent()))
+		w.Equal(entry.Depth-1, entry.Parent.Depth)
+		w.True(entry.Parent.Info.IsDir())
+		if entry.Depth == 0 {
+			w.True(entry.Parent.Path.Equals(w.root))
+			w.Nil(entry.Parent.Parent)
+		}
+	}
+}
+
+func TestWalkSymlinkLoop(t *testing.T) {
+	for _, algorithm := range []Algorithm{AlgorithmBasic, AlgorithmPostOrderDepthFirst, AlgorithmPreOrderDepthFirst} {
+		t.Run(fmt.Sprintf("algorithm %d", algorithm), func(t *testing.T) {
+			root := NewPath(t.TempDir())
+			require.NoError(t, root.Join("a", "b").MkdirAll())
+			require.NoError(t, root.Join("a", "b", "file.txt").WriteFile([]byte("")))
+			require.NoError(t, root.Join("a", "b", "loop").Symlink(root.Join("a")))
+
+			walker, err := NewWalk(root, WalkAlgorithm(algorithm), WalkFollowSymlinks(true))
+			require.NoError(t, err)
+
+			visited := map[string]error{}
+			require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+				rel, relErr := path.RelativeTo(root)
+				require.NoError(t, relErr)
+				visited[rel.String()] = err
+				return nil
+			}))
+			assert.Len(t, visited, 4)
+			assert.NoError(t, visited["a"])
+			assert.NoError(t, visited["a/b"])
+			assert.NoError(t, visited["a/b/file.txt"])
+			assert.True(t, errors.Is(visited["a/b/loop"], ErrWalkSymlinkLoop), "unexpected error: %v", visited["a/b/loop"])
+		})
+	}
+}
+
+func TestWalkMaxSymlinkHops(t *testing.T) {
+	root := NewPath(t.TempDir())
+	for _, dir := range []string{"a", "b", "c"} {
+		require.NoError(t, root.Join(dir).Mkdir())
+	}
+	require.NoError(t, root.Join("c", "file.txt").WriteFile([]byte("")))
+	require.NoError(t, root.Join("a", "tob").Symlink(root.Join("b")))
+	require.NoError(t, root.Join("b", "toc").Symlink(root.Join("c")))
+
+	walker, err := NewWalk(root, WalkFollowSymlinks(true), WalkMaxSymlinkHops(1), WalkVisitDirs(false))
+	require.NoError(t, err)
+
+	visited := []string{}
+	require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		rel, relErr := path.RelativeTo(root)
+		require.NoError(t, relErr)
+		visited = append(visited, rel.String())
+		return nil
+	}))
+	slices.Sort(visited)
+	assert.Equal(t, []string{"b/toc/file.txt", "c/file.txt"}, visited)
+}
+
+func TestWalkSameDevice(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+
+	walker, err := NewWalk(root, WalkSameDevice(true))
+	require.NoError(t, err)
+	walkFunc := MockWalkFunc{}
+	walkFunc.On("Execute", mock.Anything, mock.Anything, nil).Return(nil)
+	require.NoError(t, walker.Walk(walkFunc.Execute))
+	walkFunc.AssertNumberOfCalls(t, "Execute", 5)
+}
+
+func TestWalkSameDeviceNotPossible(t *testing.T) {
+	root := NewPath("/", PathWithAfero(afero.NewMemMapFs()))
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+
+	walker, err := NewWalk(root, WalkSameDevice(true))
+	require.NoError(t, err)
+	walkFunc := MockWalkFunc{}
+	walkFunc.On("Execute", mock.Anything, mock.Anything, nil).Return(nil)
+	err = walker.Walk(walkFunc.Execute)
+	assert.True(t, errors.Is(err, ErrSameDeviceNotPossible), "unexpected error: %v", err)
+	walkFunc.AssertNumberOfCalls(t, "Execute", 5)
+}
+
+func TestWalkSkipMountPoints(t *testing.T) {
+	root := NewPath(t.TempDir())
+	require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+
+	walker, err := NewWalk(root, WalkSkipMountPoints(root.Join("subdir").String()+"/"))
+	require.NoError(t, err)
+	visited := []string{}
+	require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+		visited = append(visited, path.Name())
+		return nil
+	}))
+	slices.Sort(visited)
+	assert.Equal(t, []string{"file0.txt", "file1.txt", "subdir"}, visited)
+}
+
+func TestWalkLazyStat(t *testing.T) {
+	for _, fs := range []afero.Fs{afero.NewOsFs(), afero.NewMemMapFs()} {
+		t.Run(getFsName(fs), func(t *testing.T) {
+			root := NewPath(t.TempDir(), PathWithAfero(fs))
+			require.NoError(t, TwoFilesAtRootTwoInSubdir(root))
+			require.NoError(t, root.Join("large.txt").WriteFile([]byte("this file is larger than the others")))
+
+			walk := func(opts ...WalkOptsFunc) map[string]int64 {
+				walker, err := NewWalk(root, opts...)
+				require.NoError(t, err)
+				visited := map[string]int64{}
+				require.NoError(t, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+					require.NoError(t, err)
+					visited[path.String()] = info.Size()
+					return nil
+				}))
+				return visited
+			}
+			for _, opts := range [][]WalkOptsFunc{
+				{},
+				{WalkVisitDirs(false)},
+				{WalkMinimumFileSize(20)},
+			} {
+				expected := walk(opts...)
+				assert.Equal(t, expected, walk(append(opts, WalkLazyStat(true))...))
+			}
+		})
+	}
+}
+
+func BenchmarkWalkLazyStat(b *testing.B) {
+	root := NewPath(b.TempDir())
+	// Create a tree of 100 directories that each contain 1000 files.
+	for i := 0; i < 100; i++ {
+		dir := root.Join(fmt.Sprintf("dir%d", i))
+		require.NoError(b, dir.Mkdir())
+		for j := 0; j < 1000; j++ {
+			require.NoError(b, dir.Join(fmt.Sprintf("file%d", j)).WriteFile([]byte{}))
+		}
+	}
+	for _, lazyStat := range []bool{false, true} {
+		b.Run(fmt.Sprintf("LazyStat=%t", lazyStat), func(b *testing.B) {
+			walker, err := NewWalk(root, WalkLazyStat(lazyStat))
+			require.NoError(b, err)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				count := 0
+				require.NoError(b, walker.Walk(func(path *Path, info os.FileInfo, err error) error {
+					count++
+					return nil
+				}))
+				require.Equal(b, 100100, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,465 @@
+package pathlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WatchOp describes the kind of change reported by a WatchEvent. Several changes
+// to the same object may be combined into a single event.
+type WatchOp uint32
+
+const (
+	// WatchCreate indicates that the object was created.
+	WatchCreate WatchOp = 1 << iota
+	// WatchWrite indicates that the contents of the object were modified.
+	WatchWrite
+	// WatchRemove indicates that the object was removed.
+	WatchRemove
+	// WatchRename indicates that the object was renamed from WatchEvent.OldPath.
+	WatchRename
+	// WatchChmod indicates that the permissions of the object were modified.
+	WatchChmod
+	// WatchResync indicates that changes may have been lost, for instance because
+	// the watcher couldn't keep up with them. WatchEvent.Path is then the watched
+	// path, which callers should scan again to find its current state.
+	WatchResync
+)
+
+var watchOpNames = []string{"CREATE", "WRITE", "REMOVE", "RENAME", "CHMOD", "RESYNC"}
+
+func (op WatchOp) String() string {
+	names := []string{}
+	for i, name := range watchOpNames {
+		if op&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "NONE"
+	}
+	return strings.Join(names, "|")
+}
+
+// Has returns whether op includes all of the changes in other.
+func (op WatchOp) Has(other WatchOp) bool {
+	return op&other == other
+}
+
+// WatchEvent describes a change to an object beneath a watched path.
+type WatchEvent struct {
+	// Path is the path of the object that changed.
+	Path *Path
+	// OldPath is the previous path of the object, if Op includes WatchRename.
+	OldPath *Path
+	// Op describes the changes to the object.
+	Op WatchOp
+	// Err, if set, is an error the watcher encountered. Path is then the watched
+	// path, and Op is zero. The watcher keeps running after an error.
+	Err error
+}
+
+// WatchBackend is the mechanism that Path.Watch uses to find changes.
+type WatchBackend int
+
+const (
+	// WatchBackendPoll periodically walks the tree to find changes. It works on
+	// every filesystem.
+	WatchBackendPoll WatchBackend = iota
+	// WatchBackendInotify uses the inotify API of Linux. It's only supported on
+	// Linux, for paths on an afero.OsFs.
+	WatchBackendInotify
+	// WatchBackendAuto uses WatchBackendInotify if it's supported for the path,
+	// and WatchBackendPoll otherwise.
+	WatchBackendAuto
+)
+
+// WatchOpts is the configuration of Path.Watch.
+type WatchOpts struct {
+	// Backend is the mechanism used to find changes.
+	Backend WatchBackend
+
+	// Interval is the time between two consecutive scans of the watched tree, when
+	// using WatchBackendPoll.
+	Interval time.Duration
+
+	// Debounce is the time that changes to the tree are held back for, so that
+	// several changes to the same object are reported as a single event. Events are
+	// delivered once no changes have been seen for Debounce. If zero, the changes
+	// found by every scan are delivered immediately. Debounce only applies to
+	// WatchBackendPoll.
+	Debounce time.Duration
+
+	// Recursive specifies that the whole tree beneath the watched path should be
+	// watched. Otherwise, only its immediate children are watched.
+	Recursive bool
+
+	// WalkOpts configure the walk that scans the tree, so that its filters
+	// determine which objects are watched.
+	WalkOpts []WalkOptsFunc
+}
+
+// DefaultWatchOpts returns the default WatchOpts used by Path.Watch, which
+// recursively scan the tree every second.
+func DefaultWatchOpts() *WatchOpts {
+	return &WatchOpts{
+		Backend:   WatchBackendPoll,
+		Interval:  time.Second,
+		Recursive: true,
+	}
+}
+
+// WatchOptsFunc is a function that modifies WatchOpts.
+type WatchOptsFunc func(opts *WatchOpts)
+
+func WatchWithBackend(backend WatchBackend) WatchOptsFunc {
+	return func(config *WatchOpts) {
+		config.Backend = backend
+	}
+}
+
+func WatchInterval(interval time.Duration) WatchOptsFunc {
+	return func(config *WatchOpts) {
+		config.Interval = interval
+	}
+}
+
+func WatchDebounce(debounce time.Duration) WatchOptsFunc {
+	return func(config *WatchOpts) {
+		config.Debounce = debounce
+	}
+}
+
+func WatchRecursive(value bool) WatchOptsFunc {
+	return func(config *WatchOpts) {
+		config.Recursive = value
+	}
+}
+
+func WatchWalkOpts(opts ...WalkOptsFunc) WatchOptsFunc {
+	return func(config *WatchOpts) {
+		config.WalkOpts = append(config.WalkOpts, opts...)
+	}
+}
+
+// Watch watches the tree beneath the path for changes until ctx is done, and
+// returns a channel of the changes it finds. The channel is closed once ctx is
+// done. The events are the same regardless of the backend, so that backends can
+// be swapped freely, but each backend has its own caveats.
+//
+// With WatchBackendPoll, changes are found by periodically walking the tree and
+// comparing the state of its objects to that of the previous walk, using only
+// afero operations, so that any filesystem can be watched. An object whose size
+// or modification time changes is reported with WatchWrite. On filesystems that
+// expose inode numbers, an object that is removed while another with the same
+// inode number is created is reported with WatchRename, otherwise renames are
+// reported as a WatchRemove and a WatchCreate. An object replaced by one of
+// another type is likewise reported as a WatchRemove and a WatchCreate. Changes
+// that are undone before the next scan, and changes to the path itself, are not
+// reported. The events found by a single scan are ordered by path. Note that
+// the MemMapFs of afero 1.4 doesn't lock a file while truncating it, so
+// truncating a file of a MemMapFs while it's being scanned, for instance with
+// Path.WriteFile, is a data race.
+//
+// The tree is scanned once before Watch returns, so that all changes made after
+// it returns are reported.
+//
+// With WatchBackendInotify, directories are watched as they appear, and objects
+// that are created in a new directory before it's watched are reported with
+// WatchCreate when it is. Renames within the tree are reported with WatchRename,
+// objects moved out of it with WatchRemove, and objects moved into it with
+// WatchCreate. Changes to the attributes of an object, such as its modification
+// time, are reported with WatchChmod. If the kernel drops events because they
+// aren't read fast enough, an event with WatchResync is reported. Symlinks to
+// directories are not followed.
+func (p *Path) Watch(ctx context.Context, opts ...WatchOptsFunc) (<-chan WatchEvent, error) {
+	config := DefaultWatchOpts()
+	for _, opt := range opts {
+		opt(config)
+	}
+	walkOpts := append([]WalkOptsFunc{}, config.WalkOpts...)
+	if !config.Recursive {
+		walkOpts = append(walkOpts, WalkDepth(0))
+	}
+	walker, err := NewWalk(p, walkOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Backend {
+	case WatchBackendPoll:
+	case WatchBackendInotify:
+		return watchInotify(ctx, p, walker)
+	case WatchBackendAuto:
+		events, err := watchInotify(ctx, p, walker)
+		if !errors.Is(err, ErrWatchBackendNotSupported) {
+			return events, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrWatchBackendNotSupported, config.Backend)
+	}
+
+	w, err := newPollWatcher(p, walker, config.Debounce)
+	if err != nil {
+		return nil, err
+	}
+	go w.run(ctx, config.Interval)
+	return w.events, nil
+}
+
+// watchedObject is the state of an object, as seen by a scan of the pollWatcher.
+type watchedObject struct {
+	path    *Path
+	mode    os.FileMode
+	size    int64
+	modTime time.Time
+	// key identifies the object by its device and inode numbers, if hasKey is
+	// set.
+	key    fileKey
+	hasKey bool
+}
+
+// watchChange is a change found by comparing two scans of a tree.
+type watchChange struct {
+	WatchEvent
+	// typ is the type of the object the event refers to, which for WatchRemove
+	// is the object that was removed.
+	typ os.FileMode
+}
+
+// pollWatcher implements Path.Watch by periodically walking the watched tree.
+type pollWatcher struct {
+	root     *Path
+	walker   *Walk
+	debounce time.Duration
+	events   chan WatchEvent
+	// state maps the relative paths of the objects found by the previous scan
+	// to their state.
+	state map[string]watchedObject
+	// queue is the changes that are held back by the debounce.
+	queue []watchChange
+	// pending maps the paths of the objects in queue to their index.
+	pending map[string]int
+	// lastChange is the time of the most recent change in queue.
+	lastChange time.Time
+}
+
+// newPollWatcher returns a pollWatcher that has scanned the tree once.
+func newPollWatcher(root *Path, walker *Walk, debounce time.Duration) (*pollWatcher, error) {
+	w := &pollWatcher{
+		root:     root,
+		walker:   walker,
+		debounce: debounce,
+		events:   make(chan WatchEvent),
+		pending:  map[string]int{},
+	}
+	var err error
+	if w.state, err = w.scan(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *pollWatcher) run(ctx context.Context, interval time.Duration) {
+	defer close(w.events)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !w.poll(ctx, now) {
+				return
+			}
+		}
+	}
+}
+
+// poll scans the tree and delivers the events that are due. It returns false if
+// ctx was done while delivering them.
+func (w *pollWatcher) poll(ctx context.Context, now time.Time) bool {
+	state, err := w.scan()
+	if err != nil {
+		return w.send(ctx, WatchEvent{Path: w.root, Err: err})
+	}
+	changes := diffWatchedObjects(w.state, state)
+	w.state = state
+	for _, change := range changes {
+		w.enqueue(change)
+	}
+	if len(changes) > 0 {
+		w.lastChange = now
+	}
+	if len(w.queue) == 0 || now.Sub(w.lastChange) < w.debounce {
+		return true
+	}
+	queue := w.queue
+	w.queue = nil
+	w.pending = map[string]int{}
+	for _, change := range queue {
+		if change.Op == 0 {
+			continue
+		}
+		if !w.send(ctx, change.WatchEvent) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *pollWatcher) send(ctx context.Context, event WatchEvent) bool {
+	select {
+	case w.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// enqueue adds change to the queue, combining it with an earlier change to the
+// same object.
+func (w *pollWatcher) enqueue(change watchChange) {
+	if change.Op.Has(WatchRename) {
+		oldKey := change.OldPath.String()
+		if i, ok := w.pending[oldKey]; ok && w.queue[i].Op.Has(WatchCreate) {
+			// The object was renamed before its creation was delivered, so
+			// it's reported as created under its new name.
+			w.drop(oldKey, i)
+			change.WatchEvent = WatchEvent{Path: change.Path, Op: WatchCreate}
+		}
+	}
+	key := change.Path.String()
+	i, ok := w.pending[key]
+	if !ok {
+		w.pending[key] = len(w.queue)
+		w.queue = append(w.queue, change)
+		return
+	}
+	queued := &w.queue[i]
+	switch {
+	case queued.Op.Has(WatchCreate) && change.Op.Has(WatchRemove):
+		// The object was only there for a moment.
+		w.drop(key, i)
+	case queued.Op.Has(WatchCreate):
+		// Modifications of a new object are implied by its creation.
+	case queued.Op.Has(WatchRemove) && change.Op.Has(WatchCreate):
+		if queued.typ.Type() == change.typ.Type() {
+			// The object was replaced by another of the same type.
+			queued.Op = WatchWrite
+			break
+		}
+		// An object whose type changed is reported as removed and created,
+		// like it is by a single scan.
+		w.pending[key] = len(w.queue)
+		w.queue = append(w.queue, change)
+	default:
+		queued.Op |= change.Op
+		if change.OldPath != nil {
+			queued.OldPath = change.OldPath
+		}
+	}
+}
+
+// drop drops the change at index i of the queue, which is the latest change to
+// the object with the given key. The change is skipped when the queue is
+// delivered, and the object's earlier change, if any, becomes its latest.
+func (w *pollWatcher) drop(key string, i int) {
+	w.queue[i].Op = 0
+	delete(w.pending, key)
+	for j := i - 1; j >= 0; j-- {
+		if w.queue[j].Op != 0 && w.queue[j].Path.String() == key {
+			w.pending[key] = j
+			return
+		}
+	}
+}
+
+// scan walks the tree and returns the state of its objects.
+func (w *pollWatcher) scan() (map[string]watchedObject, error) {
+	state := map[string]watchedObject{}
+	err := w.walker.WalkEx(func(entry *WalkEntry) error {
+		if entry.Err != nil || entry.Info == nil {
+			// The object may have been removed during the walk, in which case
+			// the next scan reports it.
+			return nil
+		}
+		object := watchedObject{
+			path:    entry.Path,
+			mode:    entry.Info.Mode(),
+			size:    entry.Info.Size(),
+			modTime: entry.Info.ModTime(),
+		}
+		object.key.dev, object.key.ino, object.hasKey = fileID(entry.Info)
+		state[entry.relative] = object
+		return nil
+	})
+	return state, err
+}
+
+// diffWatchedObjects returns the changes that describe the differences between
+// two scans of a tree, ordered by path.
+func diffWatchedObjects(before, after map[string]watchedObject) []watchChange {
+	removed := map[fileKey]string{}
+	var changes []watchChange
+	for relative, old := range before {
+		current, ok := after[relative]
+		if ok && old.mode.Type() == current.mode.Type() {
+			var op WatchOp
+			if !old.mode.IsDir() && (old.size != current.size || !old.modTime.Equal(current.modTime)) {
+				op |= WatchWrite
+			}
+			if old.mode.Perm() != current.mode.Perm() {
+				op |= WatchChmod
+			}
+			if op != 0 {
+				changes = append(changes, watchChange{WatchEvent{Path: current.path, Op: op}, current.mode})
+			}
+			continue
+		}
+		if old.hasKey && !ok {
+			removed[old.key] = relative
+			continue
+		}
+		changes = append(changes, watchChange{WatchEvent{Path: old.path, Op: WatchRemove}, old.mode})
+	}
+
+	for relative, current := range after {
+		old, ok := before[relative]
+		if ok && old.mode.Type() == current.mode.Type() {
+			continue
+		}
+		if current.hasKey {
+			if oldRelative, renamed := removed[current.key]; renamed && before[oldRelative].mode.Type() == current.mode.Type() {
+				delete(removed, current.key)
+				changes = append(changes, watchChange{WatchEvent{Path: current.path, OldPath: before[oldRelative].path, Op: WatchRename}, current.mode})
+				continue
+			}
+		}
+		changes = append(changes, watchChange{WatchEvent{Path: current.path, Op: WatchCreate}, current.mode})
+	}
+	for _, relative := range removed {
+		old := before[relative]
+		changes = append(changes, watchChange{WatchEvent{Path: old.path, Op: WatchRemove}, old.mode})
+	}
+
+	slices.SortStableFunc(changes, func(a, b watchChange) int {
+		if cmp := strings.Compare(a.Path.String(), b.Path.String()); cmp != 0 {
+			return cmp
+		}
+		// An object whose type changed is removed before it's created.
+		switch {
+		case a.Op.Has(WatchRemove) && !b.Op.Has(WatchRemove):
+			return -1
+		case b.Op.Has(WatchRemove) && !a.Op.Has(WatchRemove):
+			return 1
+		}
+		return 0
+	})
+	return changes
+}
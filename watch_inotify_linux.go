@@ -0,0 +1,520 @@
+//go:build linux
+
+package pathlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"github.com/spf13/afero"
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask is the set of inotify events that are watched for.
+const inotifyMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DONT_FOLLOW | unix.IN_ONLYDIR
+
+// inotifyWatcher implements Path.Watch with inotify.
+type inotifyWatcher struct {
+	root   *Path
+	walker *Walk
+	ignore *IgnoreMatcher
+	file   *os.File
+	events chan WatchEvent
+	// dirs maps the watch descriptors to the relative paths of the watched
+	// directories, where the root is "".
+	dirs map[int]string
+	// watches maps the relative paths of the watched directories to their watch
+	// descriptors.
+	watches map[string]int
+	// reported is the set of relative paths of the objects that pass the
+	// filters of the walk, which are those that changes are reported for.
+	reported map[string]struct{}
+}
+
+// inotifyMove is an IN_MOVED_FROM event waiting for its IN_MOVED_TO counterpart.
+type inotifyMove struct {
+	relative string
+	isDir    bool
+}
+
+func watchInotify(ctx context.Context, root *Path, walker *Walk) (<-chan WatchEvent, error) {
+	if _, ok := root.Fs().(*afero.OsFs); !ok {
+		return nil, fmt.Errorf("%w: inotify can't watch filesystem %s", ErrWatchBackendNotSupported, getFsName(root.Fs()))
+	}
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{
+		root:     root,
+		walker:   walker,
+		file:     newInotifyFile(fd),
+		events:   make(chan WatchEvent),
+		dirs:     map[int]string{},
+		watches:  map[string]int{},
+		reported: map[string]struct{}{},
+	}
+	if len(walker.Opts.IgnoreFiles) != 0 {
+		w.ignore = NewIgnoreMatcher(root, walker.Opts.IgnoreFiles...)
+	}
+	if err := w.addTree(ctx, "", false); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		w.file.Close()
+	}()
+	go w.run(ctx)
+	return w.events, nil
+}
+
+// newInotifyFile returns a file that reads from the inotify file descriptor. As
+// the file descriptor is non-blocking, reads from the file use the runtime
+// poller, and are interrupted when the file is closed.
+func newInotifyFile(fd int) *os.File {
+	return os.NewFile(uintptr(fd), "inotify")
+}
+
+// join returns the path of the object with the given relative path.
+func (w *inotifyWatcher) join(relative string) *Path {
+	if relative == "" {
+		return w.root
+	}
+	return w.root.Join(relative)
+}
+
+// watched returns whether changes to the object with the given relative path
+// should be reported, based on the options of the walk.
+func (w *inotifyWatcher) watched(relative string, info os.FileInfo) (bool, error) {
+	opts := w.walker.Opts
+	if opts.Depth >= 0 && strings.Count(relative, "/") > opts.Depth {
+		return false, nil
+	}
+	passesPatterns, err := opts.passesPatterns(relative)
+	if err != nil || !passesPatterns {
+		return false, err
+	}
+	if info.IsDir() {
+		pruned, err := matchAnyPattern(opts.PruneDirs, relative)
+		if err != nil || pruned {
+			return false, err
+		}
+	}
+	if w.ignore != nil {
+		ignored, err := w.ignore.Match(relative, info.IsDir())
+		if err != nil || ignored {
+			return false, err
+		}
+	}
+	entry := &WalkEntry{Path: w.join(relative), Info: info, passesPatterns: true}
+	return w.walker.passesFilters(entry)
+}
+
+// recursedInto returns whether the directory with the given relative path
+// should be watched, which is the case if the walk would recurse into it.
+func (w *inotifyWatcher) recursedInto(relative string) (bool, error) {
+	opts := w.walker.Opts
+	if relative == "" {
+		return true, nil
+	}
+	if opts.Depth >= 0 && strings.Count(relative, "/") >= opts.Depth {
+		return false, nil
+	}
+	pruned, err := matchAnyPattern(opts.PruneDirs, relative)
+	if err != nil || pruned {
+		return false, err
+	}
+	if w.ignore != nil {
+		ignored, err := w.ignore.Match(relative, true)
+		if err != nil || ignored {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// stat returns the os.FileInfo of the object with the given relative path, or
+// nil if it doesn't exist.
+func (w *inotifyWatcher) stat(relative string) (os.FileInfo, error) {
+	var info os.FileInfo
+	var err error
+	if w.walker.Opts.FollowSymlinks {
+		info, err = w.join(relative).Stat()
+	} else {
+		info, err = w.join(relative).Lstat()
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return info, err
+}
+
+// addWatch watches the directory with the given relative path.
+func (w *inotifyWatcher) addWatch(relative string) error {
+	conn, err := w.file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var wd int
+	var watchErr error
+	// Control guarantees that the file descriptor stays open during the call.
+	err = conn.Control(func(fd uintptr) {
+		wd, watchErr = unix.InotifyAddWatch(int(fd), w.join(relative).String(), inotifyMask)
+	})
+	if err != nil {
+		return err
+	}
+	if watchErr != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: w.join(relative).String(), Err: watchErr}
+	}
+	w.dirs[wd] = relative
+	w.watches[relative] = wd
+	return nil
+}
+
+// removeWatches forgets the watches of the directory with the given relative
+// path and its subdirectories, which have been moved out of the tree.
+func (w *inotifyWatcher) removeWatches(relative string) {
+	conn, err := w.file.SyscallConn()
+	for dir, wd := range w.watches {
+		if dir != relative && !strings.HasPrefix(dir, relative+"/") {
+			continue
+		}
+		if err == nil {
+			_ = conn.Control(func(fd uintptr) {
+				_, _ = unix.InotifyRmWatch(int(fd), uint32(wd))
+			})
+		}
+		delete(w.watches, dir)
+		delete(w.dirs, wd)
+	}
+}
+
+// moveWatches updates the relative paths of the watched directories beneath a
+// directory that was renamed from oldRelative to relative.
+func (w *inotifyWatcher) moveWatches(oldRelative string, relative string) {
+	for dir, wd := range w.watches {
+		if dir != oldRelative && !strings.HasPrefix(dir, oldRelative+"/") {
+			continue
+		}
+		moved := relative + strings.TrimPrefix(dir, oldRelative)
+		delete(w.watches, dir)
+		w.watches[moved] = wd
+		w.dirs[wd] = moved
+	}
+}
+
+// addTree watches the directory with the given relative path, and the
+// directories beneath it that the walk would recurse into. The objects in the
+// directories that pass the filters of the walk are reported as created if report
+// is true, unless they have been reported already.
+func (w *inotifyWatcher) addTree(ctx context.Context, relative string, report bool) error {
+	if recurse, err := w.recursedInto(relative); err != nil || !recurse {
+		return err
+	}
+	if _, ok := w.watches[relative]; !ok {
+		if err := w.addWatch(relative); err != nil {
+			if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENOTDIR) {
+				// The directory was removed, or replaced, already.
+				return nil
+			}
+			return err
+		}
+	}
+	children, err := w.join(relative).ReadDir()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		childRelative := path.Join(relative, child.Name())
+		info, err := w.stat(childRelative)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			continue
+		}
+		if report {
+			err = w.update(ctx, childRelative, info, WatchCreate)
+		} else if watched, watchedErr := w.watched(childRelative, info); watchedErr != nil {
+			err = watchedErr
+		} else if watched {
+			w.reported[childRelative] = struct{}{}
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := w.addTree(ctx, childRelative, report); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) run(ctx context.Context) {
+	defer close(w.events)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			w.send(ctx, WatchEvent{Path: w.root, Err: err})
+			return
+		}
+		if err := w.handle(ctx, buf[:n]); err != nil {
+			if ctx.Err() == nil {
+				w.send(ctx, WatchEvent{Path: w.root, Err: err})
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) send(ctx context.Context, event WatchEvent) error {
+	select {
+	case w.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// update records the state of the object with the given relative path, whose
+// os.FileInfo is info, or nil if it no longer exists, and reports op for it. Like
+// with WatchBackendPoll, an object is reported as created or removed when it
+// starts or stops passing the filters of the walk, and a removal is only
+// reported for objects that passed them.
+func (w *inotifyWatcher) update(ctx context.Context, relative string, info os.FileInfo, op WatchOp) error {
+	_, reported := w.reported[relative]
+	watched := false
+	if info != nil {
+		var err error
+		if watched, err = w.watched(relative, info); err != nil {
+			return err
+		}
+	}
+	switch {
+	case watched && !reported:
+		w.reported[relative] = struct{}{}
+		return w.send(ctx, WatchEvent{Path: w.join(relative), Op: WatchCreate})
+	case watched && op != WatchCreate:
+		return w.send(ctx, WatchEvent{Path: w.join(relative), Op: op})
+	case !watched && reported:
+		delete(w.reported, relative)
+		return w.send(ctx, WatchEvent{Path: w.join(relative), Op: WatchRemove})
+	}
+	return nil
+}
+
+// descendants returns the reported objects beneath the object with the given
+// relative path, ordered by path.
+func (w *inotifyWatcher) descendants(relative string) []string {
+	var descendants []string
+	for reported := range w.reported {
+		if strings.HasPrefix(reported, relative+"/") {
+			descendants = append(descendants, reported)
+		}
+	}
+	slices.Sort(descendants)
+	return descendants
+}
+
+// removed reports the removal of the object with the given relative path, and
+// of the objects beneath it.
+func (w *inotifyWatcher) removed(ctx context.Context, relative string) error {
+	if err := w.update(ctx, relative, nil, WatchRemove); err != nil {
+		return err
+	}
+	for _, descendant := range w.descendants(relative) {
+		if err := w.update(ctx, descendant, nil, WatchRemove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle handles the events read from inotify. An IN_MOVED_FROM event is
+// expected to be followed by the matching IN_MOVED_TO event within the same
+// read, otherwise the object is considered to have been moved out of the tree.
+func (w *inotifyWatcher) handle(ctx context.Context, buf []byte) error {
+	moves := map[uint32]inotifyMove{}
+	var movesOrder []uint32
+	for offset := 0; offset+unix.SizeofInotifyEvent <= len(buf); {
+		raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(raw.Len)]
+		offset += unix.SizeofInotifyEvent + int(raw.Len)
+		name := strings.TrimRight(string(nameBytes), "\x00")
+
+		if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+			if err := w.send(ctx, WatchEvent{Path: w.root, Op: WatchResync}); err != nil {
+				return err
+			}
+			// Directories may have appeared without us noticing, and the
+			// caller finds the current state of the objects by itself.
+			w.reported = map[string]struct{}{}
+			if err := w.addTree(ctx, "", false); err != nil {
+				return err
+			}
+			continue
+		}
+		dir, ok := w.dirs[int(raw.Wd)]
+		if !ok {
+			continue
+		}
+		if raw.Mask&unix.IN_IGNORED != 0 {
+			// The directory was removed.
+			delete(w.dirs, int(raw.Wd))
+			if w.watches[dir] == int(raw.Wd) {
+				delete(w.watches, dir)
+			}
+			continue
+		}
+		if name == "" {
+			// An event of the watched directory itself, which is reported by
+			// the watch of its parent.
+			continue
+		}
+		relative := path.Join(dir, name)
+		isDir := raw.Mask&unix.IN_ISDIR != 0
+
+		var err error
+		switch {
+		case raw.Mask&unix.IN_MOVED_FROM != 0:
+			if _, ok := moves[raw.Cookie]; !ok {
+				movesOrder = append(movesOrder, raw.Cookie)
+			}
+			moves[raw.Cookie] = inotifyMove{relative: relative, isDir: isDir}
+		case raw.Mask&unix.IN_MOVED_TO != 0:
+			move, ok := moves[raw.Cookie]
+			delete(moves, raw.Cookie)
+			if !ok {
+				err = w.created(ctx, relative)
+				break
+			}
+			err = w.renamed(ctx, move, relative)
+		case raw.Mask&unix.IN_CREATE != 0:
+			err = w.created(ctx, relative)
+		case raw.Mask&unix.IN_DELETE != 0:
+			err = w.removed(ctx, relative)
+		case raw.Mask&unix.IN_MODIFY != 0:
+			err = w.changed(ctx, relative, WatchWrite)
+		case raw.Mask&unix.IN_ATTRIB != 0:
+			err = w.changed(ctx, relative, WatchChmod)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, cookie := range movesOrder {
+		move, ok := moves[cookie]
+		if !ok {
+			continue
+		}
+		if move.isDir {
+			w.removeWatches(move.relative)
+		}
+		if err := w.removed(ctx, move.relative); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changed reports a change to an existing object.
+func (w *inotifyWatcher) changed(ctx context.Context, relative string, op WatchOp) error {
+	info, err := w.stat(relative)
+	if err != nil || info == nil {
+		// The object has been removed since, which is reported separately.
+		return err
+	}
+	return w.update(ctx, relative, info, op)
+}
+
+// created reports a new object, and watches it and reports its contents if
+// it's a directory.
+func (w *inotifyWatcher) created(ctx context.Context, relative string) error {
+	info, err := w.stat(relative)
+	if err != nil || info == nil {
+		return err
+	}
+	if err := w.update(ctx, relative, info, WatchCreate); err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+	return w.addTree(ctx, relative, true)
+}
+
+// renamed reports an object that was renamed within the tree. The objects
+// beneath a renamed directory keep being reported under their new paths.
+func (w *inotifyWatcher) renamed(ctx context.Context, move inotifyMove, relative string) error {
+	info, err := w.stat(relative)
+	if err != nil {
+		return err
+	}
+	_, oldReported := w.reported[move.relative]
+	delete(w.reported, move.relative)
+	if move.isDir {
+		w.moveWatches(move.relative, relative)
+		for _, descendant := range w.descendants(move.relative) {
+			delete(w.reported, descendant)
+			w.reported[relative+strings.TrimPrefix(descendant, move.relative)] = struct{}{}
+		}
+	}
+	if oldReported {
+		// Any change to the reported objects is relative to the new path.
+		w.reported[relative] = struct{}{}
+	}
+	if info == nil {
+		// The object has been removed since.
+		return w.removed(ctx, relative)
+	}
+
+	watched, err := w.watched(relative, info)
+	if err != nil {
+		return err
+	}
+	switch {
+	case watched && oldReported:
+		err = w.send(ctx, WatchEvent{Path: w.join(relative), OldPath: w.join(move.relative), Op: WatchRename})
+	case oldReported:
+		delete(w.reported, relative)
+		err = w.send(ctx, WatchEvent{Path: w.join(move.relative), Op: WatchRemove})
+	default:
+		err = w.update(ctx, relative, info, WatchCreate)
+	}
+	if err != nil || !info.IsDir() {
+		return err
+	}
+
+	if recurse, err := w.recursedInto(relative); err != nil {
+		return err
+	} else if !recurse {
+		w.removeWatches(relative)
+		for _, descendant := range w.descendants(relative) {
+			if err := w.update(ctx, descendant, nil, WatchRemove); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// The directory may have been moved somewhere we didn't watch it yet.
+	return w.addTree(ctx, relative, true)
+}
@@ -0,0 +1,182 @@
+//go:build linux
+
+package pathlib
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// waitForEvent returns the first event for path that includes op, skipping any
+// other events, and fails the test if there is none within a few seconds.
+func waitForEvent(t *testing.T, events <-chan WatchEvent, op WatchOp, path *Path) WatchEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "events channel was closed")
+			require.NoError(t, event.Err)
+			if event.Op.Has(op) && event.Path.String() == path.String() {
+				return event
+			}
+		case <-timeout:
+			require.FailNowf(t, "timed out waiting for event", "%s %s", op, path)
+		}
+	}
+}
+
+func watchInotifyTest(t *testing.T, opts ...WatchOptsFunc) (*Path, <-chan WatchEvent) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	root := NewPath(t.TempDir())
+	events, err := root.Watch(ctx, append(opts, WatchWithBackend(WatchBackendInotify))...)
+	require.NoError(t, err)
+	return root, events
+}
+
+func TestWatchInotify(t *testing.T) {
+	root, events := watchInotifyTest(t)
+
+	file := root.Join("file.txt")
+	require.NoError(t, file.WriteFile([]byte("hello")))
+	waitForEvent(t, events, WatchCreate, file)
+	waitForEvent(t, events, WatchWrite, file)
+
+	require.NoError(t, file.Chmod(0o600))
+	waitForEvent(t, events, WatchChmod, file)
+
+	renamed := root.Join("renamed.txt")
+	require.NoError(t, root.Join("file.txt").Rename(renamed))
+	event := waitForEvent(t, events, WatchRename, renamed)
+	require.NotNil(t, event.OldPath)
+	assert.Equal(t, root.Join("file.txt").String(), event.OldPath.String())
+
+	require.NoError(t, renamed.Remove())
+	waitForEvent(t, events, WatchRemove, renamed)
+}
+
+func TestWatchInotifyNewDirectories(t *testing.T) {
+	root, events := watchInotifyTest(t)
+
+	nested := root.Join("a", "b", "c")
+	require.NoError(t, nested.MkdirAll())
+	waitForEvent(t, events, WatchCreate, root.Join("a"))
+	waitForEvent(t, events, WatchCreate, nested)
+
+	file := nested.Join("file.txt")
+	require.NoError(t, file.WriteFile([]byte("hello")))
+	waitForEvent(t, events, WatchCreate, file)
+
+	require.NoError(t, root.Join("a").Rename(root.Join("moved")))
+	waitForEvent(t, events, WatchRename, root.Join("moved"))
+	movedFile := root.Join("moved", "b", "c", "file.txt")
+	require.NoError(t, movedFile.WriteFile([]byte("hello world")))
+	waitForEvent(t, events, WatchWrite, movedFile)
+}
+
+func TestWatchInotifyMoveInAndOut(t *testing.T) {
+	outside := NewPath(t.TempDir())
+	root, events := watchInotifyTest(t)
+
+	in := outside.Join("in.txt")
+	require.NoError(t, in.WriteFile([]byte("in")))
+	require.NoError(t, in.Rename(root.Join("in.txt")))
+	waitForEvent(t, events, WatchCreate, root.Join("in.txt"))
+
+	require.NoError(t, root.Join("in.txt").Rename(outside.Join("out.txt")))
+	waitForEvent(t, events, WatchRemove, root.Join("in.txt"))
+}
+
+func TestWatchInotifyFilters(t *testing.T) {
+	root, events := watchInotifyTest(t, WatchRecursive(false), WatchWalkOpts(WalkInclude("*.txt")))
+
+	require.NoError(t, root.Join("subdir").Mkdir())
+	require.NoError(t, root.Join("subdir", "nested.txt").WriteFile([]byte("nested")))
+	require.NoError(t, root.Join("build.log").WriteFile([]byte("log")))
+	require.NoError(t, root.Join("file.txt").WriteFile([]byte("file")))
+	for {
+		event := nextEvent(t, events)
+		require.Equal(t, root.Join("file.txt").String(), event.Path.String())
+		if event.Op == WatchWrite {
+			break
+		}
+	}
+	noEvent(t, events)
+}
+
+func TestWatchInotifyTypeFilters(t *testing.T) {
+	root, events := watchInotifyTest(t, WatchWalkOpts(WalkVisitDirs(false), WalkMinimumFileSize(2)))
+
+	// Objects that don't pass the filters aren't reported, even when removed.
+	dir := root.Join("dir")
+	require.NoError(t, dir.Mkdir())
+	small := dir.Join("small.txt")
+	require.NoError(t, small.WriteFile([]byte("s")))
+	require.NoError(t, small.Remove())
+	noEvent(t, events)
+
+	// An object is reported as created once it passes the filters, and as
+	// removed once it no longer does.
+	file := dir.Join("file.txt")
+	require.NoError(t, file.WriteFile([]byte("f")))
+	noEvent(t, events)
+	require.NoError(t, file.WriteFile([]byte("file")))
+	assert.Equal(t, WatchEvent{Path: file, Op: WatchCreate}, nextEvent(t, events))
+	require.NoError(t, file.WriteFile([]byte("f")))
+	assert.Equal(t, WatchEvent{Path: file, Op: WatchRemove}, nextEvent(t, events))
+
+	require.NoError(t, file.WriteFile([]byte("file")))
+	assert.Equal(t, WatchEvent{Path: file, Op: WatchCreate}, nextEvent(t, events))
+	require.NoError(t, dir.RemoveAll())
+	assert.Equal(t, WatchEvent{Path: file, Op: WatchRemove}, nextEvent(t, events))
+	noEvent(t, events)
+}
+
+func TestWatchInotifyOverflow(t *testing.T) {
+	root := NewPath(t.TempDir())
+	walker, err := NewWalk(root)
+	require.NoError(t, err)
+	w := &inotifyWatcher{
+		root:     root,
+		walker:   walker,
+		events:   make(chan WatchEvent, 1),
+		dirs:     map[int]string{},
+		watches:  map[string]int{},
+		reported: map[string]struct{}{},
+	}
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	require.NoError(t, err)
+	w.file = newInotifyFile(fd)
+	defer w.file.Close()
+
+	buf := make([]byte, unix.SizeofInotifyEvent)
+	binary.NativeEndian.PutUint32(buf[0:], ^uint32(0))
+	binary.NativeEndian.PutUint32(buf[4:], unix.IN_Q_OVERFLOW)
+	require.NoError(t, w.handle(context.Background(), buf))
+	event := <-w.events
+	assert.Equal(t, WatchResync, event.Op)
+	assert.Equal(t, root.String(), event.Path.String())
+	assert.Contains(t, w.watches, "")
+}
+
+func TestWatchInotifyNotSupported(t *testing.T) {
+	root := NewPathAfero("/", afero.NewMemMapFs())
+	_, err := root.Watch(context.Background(), WatchWithBackend(WatchBackendInotify))
+	assert.True(t, errors.Is(err, ErrWatchBackendNotSupported))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := root.Watch(ctx, WatchWithBackend(WatchBackendAuto), WatchInterval(time.Millisecond))
+	require.NoError(t, err)
+	appendFile(t, root.Join("file.txt"), "file")
+	assert.Equal(t, WatchCreate, nextEvent(t, events).Op)
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package pathlib
+
+import (
+	"context"
+	"fmt"
+)
+
+func watchInotify(ctx context.Context, root *Path, walker *Walk) (<-chan WatchEvent, error) {
+	return nil, fmt.Errorf("%w: inotify is only available on Linux", ErrWatchBackendNotSupported)
+}
@@ -0,0 +1,301 @@
+package pathlib
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextEvent returns the next event from the watcher, failing the test if there
+// is none within a few seconds.
+func nextEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "events channel was closed")
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for event")
+	}
+	return WatchEvent{}
+}
+
+// noEvent asserts that the watcher reports nothing for a few intervals.
+func noEvent(t *testing.T, events <-chan WatchEvent) {
+	t.Helper()
+	select {
+	case event := <-events:
+		assert.Failf(t, "unexpected event", "%s %s", event.Op, event.Path)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatchOpString(t *testing.T) {
+	assert.Equal(t, "NONE", WatchOp(0).String())
+	assert.Equal(t, "CREATE", WatchCreate.String())
+	assert.Equal(t, "WRITE|CHMOD", (WatchWrite | WatchChmod).String())
+}
+
+// appendFile appends contents to the file at p, creating it if necessary. Unlike
+// Path.WriteFile it never truncates the file, which isn't safe while a MemMapFs
+// is scanned, see Path.Watch.
+func appendFile(t *testing.T, p *Path, contents string) {
+	t.Helper()
+	file, err := p.OpenFile(os.O_WRONLY | os.O_CREATE | os.O_APPEND)
+	require.NoError(t, err)
+	_, err = file.WriteString(contents)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+// newTestPollWatcher returns a pollWatcher for root that is polled by the test
+// itself, so that the test controls when each poll happens.
+func newTestPollWatcher(t *testing.T, root *Path, debounce time.Duration, opts ...WalkOptsFunc) *pollWatcher {
+	walker, err := NewWalk(root, opts...)
+	require.NoError(t, err)
+	w, err := newPollWatcher(root, walker, debounce)
+	require.NoError(t, err)
+	w.events = make(chan WatchEvent, 10)
+	return w
+}
+
+// pollEvents polls w once at the given time and returns the events it delivered.
+func pollEvents(t *testing.T, w *pollWatcher, now time.Time) []WatchEvent {
+	require.True(t, w.poll(context.Background(), now))
+	events := []WatchEvent{}
+	for len(w.events) > 0 {
+		events = append(events, <-w.events)
+	}
+	return events
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	root := NewPathAfero("/root", afero.NewMemMapFs())
+	require.NoError(t, root.Join("subdir").MkdirAll())
+
+	events, err := root.Watch(ctx, WatchInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	file := root.Join("subdir", "file.txt")
+	appendFile(t, file, "hello")
+	event := nextEvent(t, events)
+	assert.Equal(t, WatchCreate, event.Op)
+	assert.Equal(t, file.String(), event.Path.String())
+
+	appendFile(t, file, " world")
+	event = nextEvent(t, events)
+	assert.Equal(t, WatchWrite, event.Op)
+	assert.Equal(t, file.String(), event.Path.String())
+
+	require.NoError(t, file.Chmod(0o600))
+	event = nextEvent(t, events)
+	assert.Equal(t, WatchChmod, event.Op)
+
+	require.NoError(t, file.Remove())
+	event = nextEvent(t, events)
+	assert.Equal(t, WatchRemove, event.Op)
+	assert.Equal(t, file.String(), event.Path.String())
+	noEvent(t, events)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchRename(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	root := NewPath(t.TempDir())
+	old := root.Join("old.txt")
+	require.NoError(t, old.WriteFile([]byte("contents")))
+
+	events, err := root.Watch(ctx, WatchInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	renamed := root.Join("new.txt")
+	require.NoError(t, root.Join("old.txt").Rename(renamed))
+	event := nextEvent(t, events)
+	assert.Equal(t, WatchRename, event.Op)
+	assert.Equal(t, renamed.String(), event.Path.String())
+	require.NotNil(t, event.OldPath)
+	assert.Equal(t, old.String(), event.OldPath.String())
+}
+
+func TestWatchNotRecursive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	root := NewPathAfero("/root", afero.NewMemMapFs())
+	require.NoError(t, root.Join("subdir").MkdirAll())
+
+	events, err := root.Watch(ctx, WatchInterval(time.Millisecond), WatchRecursive(false))
+	require.NoError(t, err)
+
+	appendFile(t, root.Join("subdir", "ignored.txt"), "ignored")
+	noEvent(t, events)
+	appendFile(t, root.Join("file.txt"), "file")
+	assert.Equal(t, root.Join("file.txt").String(), nextEvent(t, events).Path.String())
+}
+
+func TestWatchFilters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	root := NewPathAfero("/root", afero.NewMemMapFs())
+	require.NoError(t, root.MkdirAll())
+
+	events, err := root.Watch(ctx, WatchInterval(time.Millisecond), WatchWalkOpts(WalkInclude("*.txt")))
+	require.NoError(t, err)
+
+	appendFile(t, root.Join("build.log"), "log")
+	noEvent(t, events)
+	appendFile(t, root.Join("file.txt"), "file")
+	assert.Equal(t, root.Join("file.txt").String(), nextEvent(t, events).Path.String())
+}
+
+func TestWatchDebounce(t *testing.T) {
+	root := NewPathAfero("/root", afero.NewMemMapFs())
+	require.NoError(t, root.MkdirAll())
+	w := newTestPollWatcher(t, root, time.Minute)
+
+	start := time.Now()
+	ctx := context.Background()
+	created := root.Join("created.txt")
+	transient := root.Join("transient.txt")
+	require.NoError(t, created.WriteFile([]byte("a")))
+	require.NoError(t, transient.WriteFile([]byte("a")))
+	require.True(t, w.poll(ctx, start))
+	require.NoError(t, created.WriteFile([]byte("ab")))
+	require.NoError(t, transient.Remove())
+	require.True(t, w.poll(ctx, start.Add(time.Second)))
+	assert.Len(t, w.events, 0, "events should be held back until the tree settles")
+
+	require.True(t, w.poll(ctx, start.Add(time.Second+time.Minute)))
+	require.Len(t, w.events, 1)
+	event := <-w.events
+	assert.Equal(t, WatchCreate, event.Op)
+	assert.Equal(t, created.String(), event.Path.String())
+}
+
+func TestWatchReplaced(t *testing.T) {
+	root := NewPathAfero("/root", afero.NewMemMapFs())
+	file := root.Join("file")
+	other := root.Join("other")
+	appendFile(t, file, "a")
+	appendFile(t, other, "a")
+	start := time.Now()
+
+	// An object replaced by one of another type within a single scan is
+	// reported as removed and created.
+	w := newTestPollWatcher(t, root, 0)
+	require.NoError(t, file.Remove())
+	require.NoError(t, file.MkdirAll())
+	assert.Equal(t, []WatchEvent{{Path: file, Op: WatchRemove}, {Path: file, Op: WatchCreate}}, pollEvents(t, w, start))
+
+	// The same goes for changes that are debounced, whereas an object replaced
+	// by one of the same type is reported as written.
+	w = newTestPollWatcher(t, root, time.Minute)
+	require.NoError(t, file.Remove())
+	require.NoError(t, other.Remove())
+	assert.Empty(t, pollEvents(t, w, start))
+	appendFile(t, file, "b")
+	appendFile(t, other, "b")
+	assert.Empty(t, pollEvents(t, w, start.Add(time.Second)))
+	assert.Equal(t, []WatchEvent{
+		{Path: file, Op: WatchRemove},
+		{Path: other, Op: WatchWrite},
+		{Path: file, Op: WatchCreate},
+	}, pollEvents(t, w, start.Add(time.Second+time.Minute)))
+
+	// An object that is only there for a moment is dropped, leaving the
+	// object it replaced.
+	require.NoError(t, file.Remove())
+	assert.Empty(t, pollEvents(t, w, start.Add(time.Hour)))
+	require.NoError(t, file.MkdirAll())
+	assert.Empty(t, pollEvents(t, w, start.Add(time.Hour+time.Second)))
+	require.NoError(t, file.Remove())
+	assert.Empty(t, pollEvents(t, w, start.Add(time.Hour+2*time.Second)))
+	appendFile(t, file, "c")
+	assert.Empty(t, pollEvents(t, w, start.Add(time.Hour+3*time.Second)))
+	assert.Equal(t, []WatchEvent{{Path: file, Op: WatchWrite}}, pollEvents(t, w, start.Add(2*time.Hour)))
+}
+
+func TestWatchDebounceRename(t *testing.T) {
+	root := NewPath(t.TempDir())
+	w := newTestPollWatcher(t, root, time.Minute)
+	start := time.Now()
+
+	// A new object that is renamed before its creation is delivered is only
+	// reported as created under its new name.
+	old := root.Join("old.txt")
+	renamed := root.Join("new.txt")
+	require.NoError(t, old.WriteFile([]byte("a")))
+	assert.Empty(t, pollEvents(t, w, start))
+	require.NoError(t, root.Join("old.txt").Rename(renamed))
+	assert.Empty(t, pollEvents(t, w, start.Add(time.Second)))
+	events := pollEvents(t, w, start.Add(time.Second+time.Minute))
+	require.Len(t, events, 1)
+	assert.Equal(t, WatchCreate, events[0].Op)
+	assert.Equal(t, renamed.String(), events[0].Path.String())
+	assert.Nil(t, events[0].OldPath)
+}
+
+func TestDiffWatchedObjects(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	now := time.Now()
+	object := func(name string, mode os.FileMode, size int64, ino uint64) watchedObject {
+		return watchedObject{
+			path:    NewPathAfero(name, fs),
+			mode:    mode,
+			size:    size,
+			modTime: now,
+			key:     fileKey{dev: 1, ino: ino},
+			hasKey:  ino != 0,
+		}
+	}
+	before := map[string]watchedObject{
+		"changed": object("changed", 0o644, 1, 0),
+		"chmod":   object("chmod", 0o644, 1, 0),
+		"dir":     object("dir", os.ModeDir|0o755, 1, 0),
+		"old":     object("old", 0o644, 1, 10),
+		"removed": object("removed", 0o644, 1, 0),
+		"retyped": object("retyped", 0o644, 1, 0),
+		"same":    object("same", 0o644, 1, 0),
+	}
+	after := map[string]watchedObject{
+		"changed": object("changed", 0o644, 2, 0),
+		"chmod":   object("chmod", 0o600, 1, 0),
+		"created": object("created", 0o644, 1, 0),
+		"dir":     object("dir", os.ModeDir|0o755, 2, 0),
+		"new":     object("new", 0o644, 1, 10),
+		"retyped": object("retyped", os.ModeDir|0o755, 1, 0),
+		"same":    object("same", 0o644, 1, 0),
+	}
+
+	type change struct {
+		path    string
+		oldPath string
+		op      WatchOp
+	}
+	var changes []change
+	for _, event := range diffWatchedObjects(before, after) {
+		c := change{path: event.Path.String(), op: event.Op}
+		if event.OldPath != nil {
+			c.oldPath = event.OldPath.String()
+		}
+		changes = append(changes, c)
+	}
+	assert.Equal(t, []change{
+		{path: "changed", op: WatchWrite},
+		{path: "chmod", op: WatchChmod},
+		{path: "created", op: WatchCreate},
+		{path: "new", oldPath: "old", op: WatchRename},
+		{path: "removed", op: WatchRemove},
+		{path: "retyped", op: WatchRemove},
+		{path: "retyped", op: WatchCreate},
+	}, changes)
+}